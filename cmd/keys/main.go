@@ -0,0 +1,110 @@
+// Command keys manages the API keys accepted by the server.
+//
+//	keys [-db keys.db] create -name <name> -role read|write|admin
+//	keys [-db keys.db] list
+//	keys [-db keys.db] revoke <id>
+//	keys hash <key>
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: keys [-db file] <command> [arguments]
+
+Commands:
+  create -name <name> -role <read|write|admin>   issue a new key and print it once
+  list                                           list keys, including revoked ones
+  revoke <id>                                    revoke a key
+  hash <key>                                     print the hash of a key for the auth.keys config
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	dbPath := flag.String("db", "keys.db", "key database")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	if cmd == "hash" {
+		if len(args) != 1 {
+			log.Fatal("usage: keys hash <key>")
+		}
+		fmt.Println(auth.HashKey(args[0]))
+		return
+	}
+
+	store, err := auth.OpenSQLiteKeyStore(*dbPath)
+	if err != nil {
+		log.Fatalf("could not open key database: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	switch cmd {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ExitOnError)
+		name := fs.String("name", "", "who or what the key is for")
+		roleName := fs.String("role", string(auth.RoleRead), "read, write or admin")
+		fs.Parse(args)
+		if *name == "" {
+			log.Fatal("create: -name is required")
+		}
+		role, err := auth.ParseRole(*roleName)
+		if err != nil {
+			log.Fatalf("create: %v", err)
+		}
+		key, k, err := store.Create(ctx, *name, role)
+		if err != nil {
+			log.Fatalf("create: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Created key %d for %q with role %s. Store it now, it cannot be shown again:\n", k.ID, k.Name, k.Role)
+		fmt.Println(key)
+	case "list":
+		keys, err := store.List(ctx)
+		if err != nil {
+			log.Fatalf("list: %v", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tROLE\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.Role, k.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		tw.Flush()
+	case "revoke":
+		if len(args) != 1 {
+			log.Fatal("usage: keys revoke <id>")
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatalf("revoke: invalid key ID %q", args[0])
+		}
+		if err := store.Revoke(ctx, id); err != nil {
+			log.Fatalf("revoke: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Revoked key %d\n", id)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -1,101 +1,69 @@
+// Command seed loads data/titanic.csv into a SQLite or PostgreSQL database,
+// creating the passengers table if needed and replacing its content.
 package main
 
 import (
-	"database/sql"
-	"encoding/csv"
-	"io"
-	"log"
+	"context"
+	"flag"
+	"log/slog"
 	"os"
-	"strconv"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/logging"
 )
 
-func main() {
-	csvFile, err := os.Open("./data/titanic.csv")
-	if err != nil {
-		log.Fatalf("failed to open csv file 'titanic.csv': %v. Make sure it's in the root directory.", err)
-	}
-	defer csvFile.Close()
-
-	reader := csv.NewReader(csvFile)
-	reader.Read() // Skip header
+// seedable is a repository that can create its schema and import passengers.
+type seedable interface {
+	data.Importer
+	CreateSchema(ctx context.Context) error
+	Close() error
+}
 
-	db, err := sql.Open("sqlite3", "./data/titanic.db")
-	if err != nil {
-		log.Fatalf("failed to open database: %v", err)
-	}
-	defer db.Close()
+func main() {
+	logging.Setup("info")
 
-	createTableSQL := `
-	DROP TABLE IF EXISTS passengers;
-	CREATE TABLE passengers (
-		PassengerId INTEGER PRIMARY KEY,
-		Survived INTEGER,
-		Pclass INTEGER,
-		Name TEXT,
-		Sex TEXT,
-		Age REAL,
-		SibSp INTEGER,
-		Parch INTEGER,
-		Ticket TEXT,
-		Fare REAL,
-		Cabin TEXT,
-		Embarked TEXT
-	);`
+	source := flag.String("source", "sqlite", "database to seed: sqlite or postgres")
+	csvPath := flag.String("csv", "./data/titanic.csv", "Titanic-format CSV to load")
+	dbPath := flag.String("db", "./data/titanic.db", "SQLite database file")
+	dsn := flag.String("dsn", os.Getenv("DATA_POSTGRES_DSN"), "PostgreSQL connection string, defaults to $DATA_POSTGRES_DSN")
+	flag.Parse()
 
-	_, err = db.Exec(createTableSQL)
-	if err != nil {
-		log.Fatalf("failed to create table: %v", err)
+	ctx := context.Background()
+	var repo seedable
+	var err error
+	switch *source {
+	case "sqlite":
+		repo, err = data.NewSQLiteRepository(*dbPath)
+	case "postgres":
+		repo, err = data.NewPostgresRepository(ctx, data.PostgresConfig{DSN: *dsn})
+	default:
+		logging.Fatal("unknown source, valid sources are: sqlite, postgres", "source", *source)
 	}
-
-	tx, err := db.Begin()
 	if err != nil {
-		log.Fatalf("failed to begin transaction: %v", err)
+		logging.Fatal("failed to open database", "source", *source, "error", err)
 	}
+	defer repo.Close()
 
-	stmt, err := tx.Prepare(`INSERT INTO passengers(PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, 
-                       Parch, Ticket, Fare, Cabin, Embarked) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	// Reading through the CSV repository stores the passengers exactly as the
+	// csv source serves them, so both report the same dataset version.
+	csvRepo, err := data.NewCSVRepository(*csvPath)
 	if err != nil {
-		log.Fatalf("failed to prepare statement: %v", err)
+		logging.Fatal("failed to open csv file", "file", *csvPath, "error", err)
 	}
-	defer stmt.Close()
-
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Printf("error reading record: %v", err)
-			continue
-		}
-
-		args := make([]interface{}, len(record))
-		for i, val := range record {
-			if val == "" {
-				args[i] = nil
-			} else {
-				// Convert fields to their proper types
-				if i == 5 || i == 9 { // Age or Fare
-					args[i], _ = strconv.ParseFloat(val, 64)
-				} else if i == 0 || i == 1 || i == 2 || i == 6 || i == 7 { // Integer fields
-					args[i], _ = strconv.Atoi(val)
-				} else { // String fields
-					args[i] = val
-				}
-			}
-		}
-
-		_, err = stmt.Exec(args...)
+	var records []data.ImportRecord
+	for p, err := range csvRepo.Passengers(ctx) {
 		if err != nil {
-			log.Printf("failed to insert record %+v: %v", record, err)
+			logging.Fatal("failed to read csv file", "file", *csvPath, "error", err)
 		}
+		records = append(records, data.ImportRecord{Line: len(records) + 2, Passenger: p})
 	}
 
-	if err := tx.Commit(); err != nil {
-		log.Fatalf("failed to commit transaction: %v", err)
+	if err := repo.CreateSchema(ctx); err != nil {
+		logging.Fatal("failed to create table", "error", err)
 	}
-
-	log.Println("Database setup complete. titanic.db created successfully.")
+	summary, err := repo.Import(ctx, data.ImportReplace, records)
+	if err != nil {
+		logging.Fatal("failed to load passengers", "error", err)
+	}
+	slog.Info("database setup complete", "source", *source, "passengers", summary.Inserted)
 }
@@ -1,13 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+	"github.com/dhope-nagesh/titanic-go-service/internal/cache"
+	"github.com/dhope-nagesh/titanic-go-service/internal/columnar"
 	"github.com/dhope-nagesh/titanic-go-service/internal/config"
 	"github.com/dhope-nagesh/titanic-go-service/internal/data"
 	"github.com/dhope-nagesh/titanic-go-service/internal/handler"
-	"log"
+	"github.com/dhope-nagesh/titanic-go-service/internal/jobs"
+	"github.com/dhope-nagesh/titanic-go-service/internal/logging"
+	"github.com/dhope-nagesh/titanic-go-service/internal/metrics"
+	"github.com/dhope-nagesh/titanic-go-service/internal/ratelimit"
+	"github.com/dhope-nagesh/titanic-go-service/internal/tlsutil"
+	"github.com/dhope-nagesh/titanic-go-service/internal/tracing"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // @title           Titanic Passenger API
@@ -15,37 +32,373 @@ import (
 // @description     A web service to query Titanic passenger data.
 // @host            127.0.0.1:8080
 // @BasePath        /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in              header
+// @name            Authorization
+// @description     An API key or JWT sent as "Bearer <token>". API keys may also be sent in X-API-Key.
 func main() {
 	cfg, err := config.LoadConfig(".")
 	if err != nil {
-		log.Fatalf("could not load config: %v", err)
+		logging.Fatal("could not load config", "error", err)
 	}
+	if err := logging.Setup(cfg.Log.Level); err != nil {
+		logging.Fatal("invalid log configuration", "error", err)
+	}
+	logging.GinDebugLogger()
+
+	// ctx is cancelled by SIGINT or SIGTERM, which starts the shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var resources closers
 
-	var repo data.PassengerRepository
-	switch cfg.Data.Source {
-	case "csv":
-		repo, err = data.NewCSVRepository(cfg.Data.CSVFile)
-		log.Println("Using CSV data source")
-	case "sqlite":
-		repo, err = data.NewSQLiteRepository(cfg.Data.DBFile)
-		log.Println("Using SQLite data source")
-	default:
-		log.Fatalf("invalid data source in config: %s", cfg.Data.Source)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		logging.Fatal("could not set up tracing", "error", err)
 	}
+	// Added first, so spans recorded while the other resources close are still flushed.
+	resources.add(closerFunc(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
+	}))
 
+	repo, failover, err := openRepository(cfg)
 	if err != nil {
-		log.Fatalf("could not initialize repository: %v", err)
+		logging.Fatal("could not initialize repository", "source", cfg.Data.Source, "error", err)
+	}
+	slog.Info("using data source", "source", cfg.Data.Source, "fallback", cfg.Data.Fallback)
+	resources.add(repo)
+	repo = tracing.TraceRepository(repo)
+
+	var m *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+		repo = m.InstrumentRepository(repo)
+		m.ObserveDataset(repo)
+		if failover != nil {
+			m.ObserveFailover(failover)
+		}
 	}
 
-	router := gin.Default()
-	apiHandler := handler.NewAPIHandler(repo)
+	opts := []handler.Option{
+		handler.WithCacheControl(cfg.HTTP.CacheControl),
+		handler.WithBatchGetLimit(cfg.HTTP.BatchGetLimit),
+		handler.WithSourceName(cfg.Data.Source),
+	}
+	if failover != nil {
+		opts = append(opts, handler.WithDataSources(failover.Backends))
+	}
+
+	// Fares are read from the columnar copy, so only the scans rebuilding it reach storage.
+	if cfg.Columnar.Enabled {
+		repo = columnar.NewRepository(repo)
+	}
+
+	// Outermost, so that the repository metrics and spans count only the calls reaching storage.
+	if cfg.Cache.Enabled {
+		cached := cache.NewRepository(repo, cache.Config{TTL: cfg.Cache.TTL, MaxEntries: cfg.Cache.MaxEntries})
+		repo = cached
+		opts = append(opts, handler.WithCache(cached))
+		if m != nil {
+			m.ObserveCache(cached)
+		}
+		slog.Info("repository cache enabled", "ttl", cfg.Cache.TTL.String(), "max_entries", cfg.Cache.MaxEntries)
+	}
+
+	if cfg.Auth.Enabled {
+		authenticator, err := newAuthenticator(ctx, cfg, &resources)
+		if err != nil {
+			logging.Fatal("could not set up authentication", "error", err)
+		}
+		opts = append(opts, handler.WithAuth(authenticator))
+		slog.Info("authentication enabled")
+	}
+
+	if cfg.RateLimit.Enabled {
+		policy, err := newRateLimitPolicy(cfg, &resources)
+		if err != nil {
+			logging.Fatal("could not set up rate limiting", "error", err)
+		}
+		opts = append(opts, handler.WithRateLimit(policy))
+	}
+
+	if cfg.Jobs.Enabled {
+		store, err := jobs.OpenStore(cfg.Jobs.DBFile)
+		if err != nil {
+			logging.Fatal("could not open job store", "error", err)
+		}
+		resources.add(store)
+		manager, err := jobs.NewManager(store, jobs.Config{
+			Workers:   cfg.Jobs.Workers,
+			QueueSize: cfg.Jobs.QueueSize,
+			ResultTTL: cfg.Jobs.ResultTTL,
+			ResultDir: cfg.Jobs.ResultDir,
+		})
+		if err != nil {
+			logging.Fatal("could not create job manager", "error", err)
+		}
+		manager.Register("bootstrap", jobs.BootstrapRunner(repo), jobs.ValidateBootstrapParams)
+		manager.Register("cross_validation", jobs.CrossValidationRunner(repo), jobs.ValidateCrossValidationParams)
+		manager.Register("export", jobs.ExportRunner(repo), jobs.ValidateExportParams)
+		if err := manager.Start(); err != nil {
+			logging.Fatal("could not start job manager", "error", err)
+		}
+		// Added after the store, so the workers stop before it is closed.
+		resources.add(closerFunc(func() error { manager.Close(); return nil }))
+		opts = append(opts, handler.WithJobs(manager), handler.WithHealthCheck("jobs", store.Ping))
+		slog.Info("job workers started", "workers", cfg.Jobs.Workers)
+	}
+
+	router := gin.New()
+	// Anonymous clients are rate limited by IP, so only known proxies may set it through X-Forwarded-For.
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logging.Fatal("invalid trusted proxies", "error", err)
+	}
+	router.Use(logging.RequestIDMiddleware())
+	serviceName := cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = tracing.DefaultServiceName
+	}
+	// The access log runs inside the request span, so its line carries the trace ID.
+	router.Use(otelgin.Middleware(serviceName), logging.AccessLog(), logging.Recovery())
+	if m != nil {
+		router.Use(m.Middleware())
+	}
+	apiHandler := handler.NewAPIHandler(repo, opts...)
 	apiHandler.RegisterRoutes(router)
 
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
-	log.Printf("Server starting on http://localhost%s", addr)
-	log.Printf("Swagger UI available at http://localhost%s/swagger/index.html", addr)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	scheme := "http"
+	if cfg.TLS.Enabled {
+		clientAuth, err := tlsutil.ParseClientAuth(cfg.TLS.ClientAuth)
+		if err != nil {
+			logging.Fatal("invalid TLS configuration", "error", err)
+		}
+		reloader, err := tlsutil.NewReloader(tlsutil.Options{
+			CertFile:     cfg.TLS.CertFile,
+			KeyFile:      cfg.TLS.KeyFile,
+			ClientAuth:   clientAuth,
+			ClientCAFile: cfg.TLS.ClientCAFile,
+		})
+		if err != nil {
+			logging.Fatal("could not load TLS certificates", "error", err)
+		}
+		reloader.Start(ctx, cfg.TLS.ReloadInterval)
+		srv.TLSConfig = reloader.TLSConfig()
+		scheme = "https"
+		slog.Info("TLS enabled", "client_auth", string(clientAuth))
+	}
+
+	slog.Info("server starting", "url", fmt.Sprintf("%s://localhost%s", scheme, addr),
+		"swagger", fmt.Sprintf("%s://localhost%s/swagger/index.html", scheme, addr))
+
+	serveErr := make(chan error, 2)
+	go func() {
+		if cfg.TLS.Enabled {
+			// The certificate comes from srv.TLSConfig, so no files are passed here.
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	// Metrics are served on their own port, so they can stay internal to the cluster.
+	var adminSrv *http.Server
+	if m != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		adminSrv = &http.Server{
+			Addr:              fmt.Sprintf(":%s", cfg.Metrics.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() { serveErr <- adminSrv.ListenAndServe() }()
+		slog.Info("metrics available", "url", fmt.Sprintf("http://localhost%s/metrics", adminSrv.Addr))
+	}
+
+	select {
+	case err := <-serveErr:
+		resources.closeAll()
+		logging.Fatal("failed to run server", "error", err)
+	case <-ctx.Done():
+	}
+	stop() // A second signal kills the process without waiting for the drain.
+
+	timeout := cfg.Server.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	slog.Info("shutting down, draining requests", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("requests still running after the shutdown timeout were cut off", "timeout", timeout.String(), "error", err)
+		srv.Close()
+	}
+	if adminSrv != nil {
+		// Scrapes are short; whatever is left of the deadline is plenty.
+		adminSrv.Shutdown(shutdownCtx)
+	}
+	resources.closeAll()
+	slog.Info("server stopped")
+}
+
+// defaultShutdownTimeout bounds the drain when server.shutdown_timeout is unset.
+const defaultShutdownTimeout = 25 * time.Second
+
+// closers collects the resources to release once the server has stopped.
+type closers []io.Closer
+
+func (c *closers) add(closer io.Closer) {
+	*c = append(*c, closer)
+}
+
+// closeAll closes the resources in the reverse order of their creation, so
+// that nothing is closed while a later resource still depends on it.
+func (c closers) closeAll() {
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Close(); err != nil {
+			slog.Error("could not release resource during shutdown", "error", err)
+		}
+	}
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
 
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("failed to run server: %v", err)
+func (f closerFunc) Close() error { return f() }
+
+// newRateLimitPolicy builds the per-group limits and the optional daily quota.
+func newRateLimitPolicy(cfg config.Config, resources *closers) (*ratelimit.Policy, error) {
+	limits := make(map[string]ratelimit.Limit, len(cfg.RateLimit.Groups))
+	for group, l := range cfg.RateLimit.Groups {
+		limits[group] = ratelimit.Limit{Rate: l.Rate, Burst: l.Burst}
+	}
+
+	var quota *ratelimit.Quota
+	if cfg.RateLimit.DailyQuota > 0 {
+		var store ratelimit.QuotaStore = ratelimit.NewMemoryQuotaStore()
+		if cfg.RateLimit.QuotaDB != "" {
+			db, err := ratelimit.OpenSQLiteQuotaStore(cfg.RateLimit.QuotaDB)
+			if err != nil {
+				return nil, err
+			}
+			resources.add(db)
+			store = db
+		}
+		quota = ratelimit.NewQuota(cfg.RateLimit.DailyQuota, store)
+	}
+	return ratelimit.NewPolicy(limits, quota), nil
+}
+
+// openRepository opens the configured data source. With a fallback source it
+// returns a failover repository, also as its second result for metrics.
+func openRepository(cfg config.Config) (data.PassengerRepository, *data.FailoverRepository, error) {
+	if cfg.Data.Fallback == "" {
+		repo, err := data.Open(cfg.Data.Source, func(v any) error {
+			return config.DecodeDataSource(cfg.Data.Source, v)
+		})
+		return repo, nil, err
+	}
+	failover, err := data.OpenFailover(cfg.Data.Source, cfg.Data.Fallback, config.DecodeDataSource, data.FailoverConfig{
+		FailureThreshold: cfg.Data.Failover.FailureThreshold,
+		OpenTimeout:      cfg.Data.Failover.OpenTimeout,
+		Shadow:           cfg.Data.Failover.Shadow,
+		ShadowTimeout:    cfg.Data.Failover.ShadowTimeout,
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return failover, failover, nil
+}
+
+// newAuthenticator accepts, in order of precedence, JWTs, the keys listed in the
+// configuration or the key database, and client certificates.
+func newAuthenticator(ctx context.Context, cfg config.Config, resources *closers) (auth.Authenticator, error) {
+	static := make([]auth.APIKey, 0, len(cfg.Auth.Keys))
+	for _, k := range cfg.Auth.Keys {
+		role, err := auth.ParseRole(k.Role)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Name, err)
+		}
+		if len(k.Hash) != 64 {
+			return nil, fmt.Errorf("key %q: hash must be a hex SHA-256 digest", k.Name)
+		}
+		static = append(static, auth.APIKey{Name: k.Name, Hash: strings.ToLower(k.Hash), Role: role})
+	}
+	stores := auth.MultiKeyStore{auth.NewStaticKeyStore(static)}
+
+	if cfg.Auth.KeysDB != "" {
+		db, err := auth.OpenSQLiteKeyStore(cfg.Auth.KeysDB)
+		if err != nil {
+			return nil, err
+		}
+		resources.add(db)
+		stores = append(stores, db)
+	}
+	chain := auth.Chain{auth.APIKeyAuthenticator{Store: stores}}
+
+	if cfg.Auth.JWT.Enabled {
+		jwtAuth, err := newJWTAuthenticator(ctx, cfg.Auth.JWT)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(auth.Chain{jwtAuth}, chain...)
+	}
+
+	// Client certificates only count when the request carries no other credentials.
+	if len(cfg.Auth.ClientCerts) > 0 {
+		roles := make(map[string]auth.Role, len(cfg.Auth.ClientCerts))
+		for _, cc := range cfg.Auth.ClientCerts {
+			role, err := auth.ParseRole(cc.Role)
+			if err != nil {
+				return nil, fmt.Errorf("client certificate %q: %w", cc.CommonName, err)
+			}
+			roles[cc.CommonName] = role
+		}
+		chain = append(chain, auth.CertAuthenticator{Roles: roles})
+	}
+	return chain, nil
+}
+
+// newJWTAuthenticator loads the JWKS and keeps it refreshed until ctx is cancelled.
+func newJWTAuthenticator(ctx context.Context, cfg config.JWT) (*auth.JWTAuthenticator, error) {
+	roles := make(map[string]auth.Role)
+	for name, values := range cfg.Roles {
+		role, err := auth.ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("jwt roles: %w", err)
+		}
+		for _, v := range values {
+			roles[v] = role
+		}
+	}
+
+	keySet, err := auth.NewKeySet(ctx, cfg.JWKS)
+	if err != nil {
+		return nil, err
+	}
+	keySet.Start(ctx, cfg.RefreshInterval)
+
+	return auth.NewJWTAuthenticator(keySet, auth.JWTConfig{
+		Issuer:    cfg.Issuer,
+		Audience:  cfg.Audience,
+		RoleClaim: cfg.RoleClaim,
+		Roles:     roles,
+		Leeway:    cfg.Leeway,
+	})
 }
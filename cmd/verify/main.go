@@ -0,0 +1,102 @@
+// Command verify compares the passengers of data/titanic.csv with those of a
+// SQLite or PostgreSQL database, record by record and field by field. It
+// prints a report and exits with status 1 if they differ.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/logging"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+func main() {
+	// Records skipped as invalid are counted in the report, so their warnings are not needed.
+	logging.Setup("error")
+
+	source := flag.String("source", "sqlite", "database to compare with the CSV: sqlite or postgres")
+	csvPath := flag.String("csv", "./data/titanic.csv", "Titanic-format CSV")
+	dbPath := flag.String("db", "./data/titanic.db", "SQLite database file")
+	dsn := flag.String("dsn", os.Getenv("DATA_POSTGRES_DSN"), "PostgreSQL connection string, defaults to $DATA_POSTGRES_DSN")
+	maxDifferences := flag.Int("max", 50, "maximum number of field differences to print, 0 for all")
+	flag.Parse()
+
+	ctx := context.Background()
+	var db data.PassengerRepository
+	var err error
+	switch *source {
+	case "sqlite":
+		if _, statErr := os.Stat(*dbPath); statErr != nil {
+			// Opening a missing file would create an empty database.
+			logging.Fatal("cannot open database", "file", *dbPath, "error", statErr)
+		}
+		db, err = data.NewSQLiteRepository(*dbPath)
+	case "postgres":
+		db, err = data.NewPostgresRepository(ctx, data.PostgresConfig{DSN: *dsn})
+	default:
+		logging.Fatal("unknown source, valid sources are: sqlite, postgres", "source", *source)
+	}
+	if err != nil {
+		logging.Fatal("failed to open database", "source", *source, "error", err)
+	}
+	defer db.Close()
+
+	csvRepo, err := data.NewCSVRepository(*csvPath)
+	if err != nil {
+		logging.Fatal("failed to open csv file", "file", *csvPath, "error", err)
+	}
+
+	report, err := data.CompareRepositories(ctx, data.Backend{Name: "csv", Repo: csvRepo}, data.Backend{Name: *source, Repo: db}, *maxDifferences)
+	if err != nil {
+		logging.Fatal("comparison failed", "error", err)
+	}
+	writeReport(os.Stdout, report)
+	if !report.Consistent {
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+// writeReport prints report for a person to read.
+func writeReport(w io.Writer, r model.ConsistencyReport) {
+	fmt.Fprintf(w, "%s: %d passengers, %d invalid records skipped\n", r.Left, r.LeftRows, r.LeftInvalid)
+	fmt.Fprintf(w, "%s: %d passengers, %d invalid records skipped\n", r.Right, r.RightRows, r.RightInvalid)
+
+	writeIDs(w, "Only in "+r.Left, r.OnlyInLeft)
+	writeIDs(w, "Only in "+r.Right, r.OnlyInRight)
+	writeIDs(w, "Duplicate IDs", r.DuplicateIDs)
+	if r.Mismatched > 0 {
+		fmt.Fprintf(w, "\n%d passengers differ:\n", r.Mismatched)
+		width := max(len(r.Left), len(r.Right))
+		for _, d := range r.Differences {
+			fmt.Fprintf(w, "  passenger %d, %s:\n    %-*s  %s\n    %-*s  %s\n", d.PassengerID, d.Field, width, r.Left, d.Left, width, r.Right, d.Right)
+		}
+		if r.Truncated {
+			fmt.Fprintln(w, "  ... more differences not shown, raise -max to see them")
+		}
+	}
+
+	if r.Consistent {
+		fmt.Fprintf(w, "\nOK: %s and %s hold the same passengers\n", r.Left, r.Right)
+	} else {
+		fmt.Fprintf(w, "\nMISMATCH: %s and %s differ\n", r.Left, r.Right)
+	}
+}
+
+func writeIDs(w io.Writer, title string, ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	fmt.Fprintf(w, "\n%s (%d): %s\n", title, len(ids), strings.Join(s, ", "))
+}
@@ -0,0 +1,28 @@
+// Package dataset compiles the canonical Titanic passenger CSV into the
+// binary, so the service can run without a data volume.
+package dataset
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"sync"
+)
+
+// CSVFile is the name of the passenger CSV in FS.
+const CSVFile = "titanic.csv"
+
+// FS holds the embedded dataset files.
+//
+//go:embed titanic.csv
+var FS embed.FS
+
+// Checksum returns the hex SHA-256 digest of the embedded CSV file.
+var Checksum = sync.OnceValue(func() string {
+	content, err := FS.ReadFile(CSVFile)
+	if err != nil {
+		panic("dataset: " + err.Error()) // Cannot happen, the file is embedded at build time.
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+})
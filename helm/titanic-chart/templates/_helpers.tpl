@@ -52,16 +52,111 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- define "titanic-go-service.configmapdata" -}}
 server:
   port: "8080"
+  read_timeout: "30s"
+  read_header_timeout: "5s"
+  write_timeout: "2m"
+  idle_timeout: "2m"
+  shutdown_timeout: "25s"
+  trusted_proxies:
+    {{- toYaml .Values.config.trustedProxies | nindent 4 }}
+log:
+  level: {{ .Values.config.log.level | quote }}
+metrics:
+  enabled: {{ .Values.config.metrics.enabled }}
+  port: "9090"
+tracing:
+  {{- toYaml .Values.config.tracing | nindent 2 }}
+tls:
+  enabled: {{ .Values.config.tls.enabled }}
+  cert_file: "/tls/tls.crt"
+  key_file: "/tls/tls.key"
+  reload_interval: "1m"
+  client_auth: {{ .Values.config.tls.clientAuth | quote }}
+  client_ca_file: "/tls/ca.crt"
 data:
   source: "{{ .Values.config.dataSource }}"
-  csv_file: "/data/titanic.csv"
-  db_file: "/data/titanic.db"
+  fallback: {{ .Values.config.dataFallback | quote }}
+  failover:
+    {{- toYaml .Values.config.failover | nindent 4 }}
+  csv:
+    file: "/data/titanic.csv"
+  parquet:
+    file: "/data/titanic.parquet"
+  ndjson:
+    file: "/data/titanic.ndjson"
+  file:
+    file: {{ printf "/data/%s" .Values.config.dataFile | quote }}
+  http:
+    url: {{ .Values.config.http.url | quote }}
+    cache_file: {{ printf "/data/remote/%s" .Values.config.http.cacheFile | quote }}
+    refresh_interval: {{ .Values.config.http.refreshInterval | quote }}
+    timeout: {{ .Values.config.http.timeout | quote }}
+    sha256: {{ .Values.config.http.sha256 | quote }}
+    sha256_url: {{ .Values.config.http.sha256Url | quote }}
+    max_size: {{ .Values.config.http.maxSize | int64 }}
+  sqlite:
+    file: "/data/titanic.db"
+  postgres:
+    dsn: "" # Set from the DATA_POSTGRES_DSN environment variable
+    max_open_conns: {{ .Values.config.postgres.maxOpenConns }}
+    max_idle_conns: {{ .Values.config.postgres.maxIdleConns }}
+    conn_max_lifetime: {{ .Values.config.postgres.connMaxLifetime | quote }}
+    conn_max_idle_time: {{ .Values.config.postgres.connMaxIdleTime | quote }}
+    version_ttl: {{ .Values.config.postgres.versionTTL | quote }}
+cache:
+  {{- toYaml .Values.config.cache | nindent 2 }}
+columnar:
+  {{- toYaml .Values.config.columnar | nindent 2 }}
+http:
+  cache_control:
+    {{- toYaml .Values.config.cacheControl | nindent 4 }}
+jobs:
+  enabled: {{ .Values.config.jobs.enabled }}
+  db_file: "/data/jobs.db"
+  result_dir: "/data/job-results"
+  workers: {{ .Values.config.jobs.workers }}
+  queue_size: {{ .Values.config.jobs.queueSize }}
+  result_ttl: {{ .Values.config.jobs.resultTTL | quote }}
+rate_limit:
+  {{- toYaml .Values.config.rateLimit | nindent 2 }}
+auth:
+  enabled: {{ .Values.config.auth.enabled }}
+  keys_db: "/data/keys.db"
+  keys:
+    {{- toYaml .Values.config.auth.keys | nindent 4 }}
+  jwt:
+    {{- toYaml .Values.config.auth.jwt | nindent 4 }}
+{{- end -}}
+
+{{/*
+The data loader only runs when a data source reads the files of the data image,
+that is unless the embedded dataset serves on its own.
+*/}}
+{{- define "titanic-go-service.needsDataLoader" -}}
+{{- if or (ne .Values.config.dataSource "embedded") (and .Values.config.dataFallback (ne .Values.config.dataFallback "embedded")) -}}
+true
+{{- end -}}
 {{- end -}}
 
 {{- define "titanic-go-service.validateValues" -}}
-{{- $allowedDataSources := list "csv" "sqlite" -}}
+{{- if and .Values.config.tls.enabled (not .Values.config.tls.secretName) -}}
+{{- fail "config.tls.secretName is required when config.tls.enabled is true." -}}
+{{- end -}}
+{{- $allowedDataSources := list "csv" "embedded" "parquet" "ndjson" "file" "http" "sqlite" "postgres" -}}
 {{- if not (has .Values.config.dataSource $allowedDataSources) -}}
-{{- $message := printf "Invalid config.dataSource: '%s'. Allowed values are 'csv' or 'sqlite'." .Values.config.dataSource -}}
+{{- $message := printf "Invalid config.dataSource: '%s'. Allowed values are 'csv', 'embedded', 'parquet', 'ndjson', 'file', 'http', 'sqlite' or 'postgres'." .Values.config.dataSource -}}
 {{- fail $message -}}
 {{- end -}}
+{{- if and .Values.config.dataFallback (not (has .Values.config.dataFallback $allowedDataSources)) -}}
+{{- fail (printf "Invalid config.dataFallback: '%s'." .Values.config.dataFallback) -}}
+{{- end -}}
+{{- if and .Values.config.dataFallback (eq .Values.config.dataFallback .Values.config.dataSource) -}}
+{{- fail "config.dataFallback must differ from config.dataSource." -}}
+{{- end -}}
+{{- if and (eq .Values.config.dataSource "http") (not .Values.config.http.url) -}}
+{{- fail "config.http.url is required when config.dataSource is 'http'." -}}
+{{- end -}}
+{{- if and (eq .Values.config.dataSource "postgres") (not .Values.config.postgres.dsnSecret) -}}
+{{- fail "config.postgres.dsnSecret is required when config.dataSource is 'postgres'." -}}
+{{- end -}}
 {{- end -}}
@@ -0,0 +1,107 @@
+// Package auth authenticates API clients and enforces role-based access per route group.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Role is an access level. Each role includes the permissions of the roles below it.
+type Role string
+
+const (
+	RoleRead  Role = "read"
+	RoleWrite Role = "write"
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{RoleRead: 1, RoleWrite: 2, RoleAdmin: 3}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("invalid role %q, valid roles are: read, write, admin", s)
+	}
+	return role, nil
+}
+
+// Allows reports whether r grants the access level required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// ID uniquely identifies the credential, e.g. "key:12".
+	ID string
+	// Name is a human-readable label for the caller.
+	Name string
+	Role Role
+}
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials at all.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned for unknown, revoked or malformed credentials.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator resolves the credentials carried by a request to a principal.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// principalKey is the gin context key holding the authenticated Principal.
+const principalKey = "auth.principal"
+
+// PrincipalFrom returns the principal authenticated for the request, if any.
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+// Require returns middleware that authenticates the request and rejects it
+// unless the caller holds at least the given role.
+func Require(a Authenticator, role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := PrincipalFrom(c)
+		if !ok {
+			var err error
+			p, err = a.Authenticate(c.Request)
+			if err != nil {
+				c.Header("WWW-Authenticate", `Bearer realm="titanic"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Message: "A valid API key or token is required"})
+				return
+			}
+			c.Set(principalKey, p)
+		}
+		if !p.Role.Allows(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{
+				Message: fmt.Sprintf("This operation requires the %s role", role),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// credential extracts an API key from the X-API-Key header or a bearer token.
+func credential(r *http.Request) (string, bool) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		return strings.TrimSpace(token), true
+	}
+	return "", false
+}
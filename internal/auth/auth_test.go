@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleWrite))
+	assert.True(t, RoleWrite.Allows(RoleRead))
+	assert.True(t, RoleRead.Allows(RoleRead))
+	assert.False(t, RoleRead.Allows(RoleWrite))
+	assert.False(t, RoleWrite.Allows(RoleAdmin))
+	assert.False(t, Role("").Allows(RoleRead))
+
+	role, err := ParseRole(" Admin ")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+	_, err = ParseRole("root")
+	assert.Error(t, err)
+}
+
+func TestSQLiteKeyStore(t *testing.T) {
+	store, err := OpenSQLiteKeyStore(filepath.Join(t.TempDir(), "keys.db"))
+	assert.NoError(t, err)
+	defer store.Close()
+	ctx := context.Background()
+
+	key, k, err := store.Create(ctx, "ci", RoleWrite)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, k.Prefix))
+	assert.NotContains(t, k.Hash, key)
+
+	got, err := store.LookupHash(ctx, HashKey(key))
+	assert.NoError(t, err)
+	assert.Equal(t, k.ID, got.ID)
+	assert.Equal(t, RoleWrite, got.Role)
+
+	_, err = store.LookupHash(ctx, HashKey("guess"))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	assert.NoError(t, store.Revoke(ctx, k.ID))
+	_, err = store.LookupHash(ctx, HashKey(key))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Error(t, store.Revoke(ctx, k.ID), "a key cannot be revoked twice")
+
+	keys, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+}
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := APIKeyAuthenticator{Store: MultiKeyStore{
+		NewStaticKeyStore([]APIKey{{Name: "reader", Hash: HashKey("r"), Role: RoleRead}}),
+		NewStaticKeyStore([]APIKey{{Name: "writer", Hash: HashKey("w"), Role: RoleWrite}}),
+	}}
+	router := gin.New()
+	group := router.Group("/", Require(a, RoleRead))
+	group.GET("/read", func(c *gin.Context) {
+		p, _ := PrincipalFrom(c)
+		c.String(http.StatusOK, p.Name)
+	})
+	group.POST("/write", Require(a, RoleWrite), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		method, path, key string
+		want              int
+	}{
+		{http.MethodGet, "/read", "", http.StatusUnauthorized},
+		{http.MethodGet, "/read", "r", http.StatusOK},
+		{http.MethodGet, "/read", "w", http.StatusOK},
+		{http.MethodPost, "/write", "r", http.StatusForbidden},
+		{http.MethodPost, "/write", "w", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Code, "%s %s with key %q", tt.method, tt.path, tt.key)
+	}
+}
+
+func TestCertAuthenticator(t *testing.T) {
+	a := CertAuthenticator{Roles: map[string]Role{"dashboard": RoleRead}}
+
+	_, err := a.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.ErrorIs(t, err, ErrNoCredentials)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "dashboard", Organization: []string{"Example"}}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	p, err := a.Authenticate(req)
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{ID: "cert:CN=dashboard,O=Example", Name: "dashboard", Role: RoleRead}, p)
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientCertificate returns the client certificate verified during the TLS
+// handshake, if the connection used mTLS.
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return r.TLS.VerifiedChains[0][0], true
+}
+
+// ClientSubject returns the subject of the request's verified client
+// certificate, e.g. "CN=dashboard,O=Example", for authorization and auditing.
+func ClientSubject(c *gin.Context) (string, bool) {
+	cert, ok := ClientCertificate(c.Request)
+	if !ok {
+		return "", false
+	}
+	return cert.Subject.String(), true
+}
+
+// CertAuthenticator authenticates requests by their verified client
+// certificate, granting roles by the certificate's common name.
+type CertAuthenticator struct {
+	// Roles maps a subject common name to its role.
+	Roles map[string]Role
+}
+
+// Authenticate implements Authenticator. A verified certificate whose common
+// name has no role authenticates but is allowed nothing.
+func (a CertAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	cert, ok := ClientCertificate(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	return Principal{
+		ID:   "cert:" + cert.Subject.String(),
+		Name: cert.Subject.CommonName,
+		Role: a.Roles[cert.Subject.CommonName],
+	}, nil
+}
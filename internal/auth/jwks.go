@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// jwk is a single JSON Web Key (RFC 7517). Only public signing keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes a JWK set into public keys by key ID. Encryption keys and
+// key types other than RSA and EC are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsaKey()
+		case "EC":
+			key, err = k.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("unsupported RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// minRefetchInterval limits how often an unknown key ID can trigger a reload,
+// so that tokens with made-up key IDs cannot flood the JWKS endpoint.
+const minRefetchInterval = time.Minute
+
+// KeySet holds the verification keys of a JWKS file or URL and reloads them
+// periodically, so that keys rotated by the issuer are picked up.
+type KeySet struct {
+	source string
+	client *http.Client
+	// group runs one load at a time, shared by the callers asking meanwhile.
+	group singleflight.Group
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+	// attemptedAt is when the last load started, whether it succeeded or not.
+	attemptedAt time.Time
+}
+
+// NewKeySet loads the JWKS at source, which is either a file path or an
+// http(s) URL. It fails if the initial load fails.
+func NewKeySet(ctx context.Context, source string) (*KeySet, error) {
+	ks := &KeySet{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+	if err := ks.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Refresh reloads the keys. On failure the previous keys stay in use.
+// Concurrent calls share a single load.
+func (ks *KeySet) Refresh(ctx context.Context) error {
+	_, err, _ := ks.group.Do("load", func() (any, error) {
+		return nil, ks.load(ctx)
+	})
+	return err
+}
+
+// load fetches and parses the key set. It must only run through ks.group.
+func (ks *KeySet) load(ctx context.Context) error {
+	ks.mu.Lock()
+	ks.attemptedAt = time.Now()
+	ks.mu.Unlock()
+
+	// Requests needing a key wait on this fetch through ks.group, so one of
+	// them hanging up must not fail it for the others.
+	data, err := ks.fetch(context.WithoutCancel(ctx))
+	if err != nil {
+		return fmt.Errorf("could not load JWKS from %s: %w", ks.source, err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(ks.source, "http://") && !strings.HasPrefix(ks.source, "https://") {
+		return os.ReadFile(ks.source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Start reloads the keys every interval until ctx is cancelled.
+func (ks *KeySet) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ks.Refresh(ctx); err != nil {
+					slog.Error("could not refresh JWKS", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Key returns the key with the given ID. A token without a key ID can only be
+// verified against a set holding a single key. An unknown ID triggers a reload,
+// in case the issuer has rotated its keys, at most once per minRefetchInterval
+// counted from the last attempt, so that failing loads are throttled too.
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, bool) {
+	ks.mu.RLock()
+	key, ok := ks.lookup(kid)
+	stale := ks.stale()
+	ks.mu.RUnlock()
+	if ok || !stale {
+		return key, ok
+	}
+
+	_, err, _ := ks.group.Do("load", func() (any, error) {
+		// Callers that found the set stale while a load was finishing must
+		// not start another one.
+		ks.mu.RLock()
+		stale := ks.stale()
+		ks.mu.RUnlock()
+		if !stale {
+			return nil, nil
+		}
+		return nil, ks.load(ctx)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "could not refresh JWKS", "kid", kid, "error", err)
+		return nil, false
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.lookup(kid)
+}
+
+// stale reports whether a reload may be attempted. It must be called with ks.mu held.
+func (ks *KeySet) stale() bool {
+	return time.Since(ks.attemptedAt) > minRefetchInterval
+}
+
+// lookup must be called with ks.mu held.
+func (ks *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if key, ok := ks.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
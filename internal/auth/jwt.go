@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig describes which tokens a JWTAuthenticator accepts and how their
+// claims map to roles.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	// RoleClaim names the claim holding the caller's roles or scopes, e.g.
+	// "roles" or "scope". It may be a string of space-separated values or an array.
+	RoleClaim string
+	// Roles maps values of RoleClaim to service roles. A token granting several
+	// roles gets the highest one.
+	Roles map[string]Role
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// signingMethods lists the accepted algorithms. Symmetric algorithms are
+// excluded since the keys come from a public JWKS.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWTAuthenticator authenticates bearer JWTs signed by a key in a JWKS.
+type JWTAuthenticator struct {
+	keys   *KeySet
+	cfg    JWTConfig
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator returns an authenticator verifying tokens against keys.
+func NewJWTAuthenticator(keys *KeySet, cfg JWTConfig) (*JWTAuthenticator, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("JWT issuer and audience must be configured")
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "roles"
+	}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	)
+	return &JWTAuthenticator{keys: keys, cfg: cfg, parser: parser}, nil
+}
+
+// Authenticate implements Authenticator. Bearer tokens that are not JWTs are
+// left to other authenticators by reporting ErrNoCredentials.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	if !ok || strings.Count(token, ".") != 2 {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := a.keys.Key(r.Context(), kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	name := sub
+	if n, ok := claims["name"].(string); ok && n != "" {
+		name = n
+	}
+	return Principal{ID: "jwt:" + sub, Name: name, Role: a.role(claims[a.cfg.RoleClaim])}, nil
+}
+
+// role returns the highest role granted by the values of the role claim. A token
+// without a mapped value authenticates but is allowed nothing.
+func (a *JWTAuthenticator) role(claim any) Role {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var best Role
+	for _, v := range values {
+		if role, ok := a.cfg.Roles[v]; ok && roleRank[role] > roleRank[best] {
+			best = role
+		}
+	}
+	return best
+}
+
+// Chain tries each authenticator in turn, moving on only when one finds no
+// credentials it understands.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if !errors.Is(err, ErrNoCredentials) {
+			return p, err
+		}
+	}
+	return Principal{}, ErrNoCredentials
+}
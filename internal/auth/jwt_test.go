@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X.Bytes()), "y": b64(key.Y.Bytes())}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return s
+}
+
+func bearer(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+var testJWTConfig = JWTConfig{
+	Issuer:    "https://idp.test/",
+	Audience:  "titanic-api",
+	RoleClaim: "roles",
+	Roles:     map[string]Role{"titanic.read": RoleRead, "titanic.admin": RoleAdmin},
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   "https://idp.test/",
+		"aud":   "titanic-api",
+		"sub":   "user-1",
+		"name":  "Ada",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": []string{"titanic.read", "titanic.admin"},
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+	keys, err := NewKeySet(context.Background(), jwksPath)
+	assert.NoError(t, err)
+	a, err := NewJWTAuthenticator(keys, testJWTConfig)
+	assert.NoError(t, err)
+
+	t.Run("valid RSA token", func(t *testing.T) {
+		p, err := a.Authenticate(bearer(sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims())))
+		assert.NoError(t, err)
+		assert.Equal(t, Principal{ID: "jwt:user-1", Name: "Ada", Role: RoleAdmin}, p)
+	})
+
+	t.Run("valid EC token with scope string", func(t *testing.T) {
+		claims := validClaims()
+		claims["roles"] = "openid titanic.read"
+		p, err := a.Authenticate(bearer(sign(t, jwt.SigningMethodES256, "ec-1", ecKey, claims)))
+		assert.NoError(t, err)
+		assert.Equal(t, RoleRead, p.Role)
+	})
+
+	t.Run("no mapped role", func(t *testing.T) {
+		claims := validClaims()
+		claims["roles"] = []string{"other"}
+		p, err := a.Authenticate(bearer(sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims)))
+		assert.NoError(t, err)
+		assert.False(t, p.Role.Allows(RoleRead))
+	})
+
+	invalid := map[string]func(jwt.MapClaims){
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.test/" },
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "other-api" },
+		"no subject":     func(c jwt.MapClaims) { delete(c, "sub") },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			mutate(claims)
+			_, err := a.Authenticate(bearer(sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims)))
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+
+	t.Run("untrusted key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		_, err = a.Authenticate(bearer(sign(t, jwt.SigningMethodRS256, "rsa-1", other, validClaims())))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("symmetric algorithm", func(t *testing.T) {
+		_, err := a.Authenticate(bearer(sign(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), validClaims())))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("not a JWT", func(t *testing.T) {
+		_, err := a.Authenticate(bearer("tgs_abc"))
+		assert.ErrorIs(t, err, ErrNoCredentials)
+	})
+
+	t.Run("rotated key after refresh", func(t *testing.T) {
+		rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		token := sign(t, jwt.SigningMethodRS256, "rsa-2", rotated, validClaims())
+
+		writeJWKS(t, jwksPath, rsaJWK("rsa-2", &rotated.PublicKey))
+		_, err = a.Authenticate(bearer(token))
+		assert.Error(t, err, "the key set was loaded too recently to refetch")
+
+		assert.NoError(t, keys.Refresh(context.Background()))
+		_, err = a.Authenticate(bearer(token))
+		assert.NoError(t, err)
+	})
+}
+
+func TestKeySet_URL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{rsaJWK("", &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	keys, err := NewKeySet(context.Background(), server.URL+"/jwks.json")
+	assert.NoError(t, err)
+	_, ok := keys.Key(context.Background(), "")
+	assert.True(t, ok, "a token without kid matches the only key")
+
+	_, err = NewKeySet(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	keys := APIKeyAuthenticator{Store: NewStaticKeyStore([]APIKey{{Name: "ci", Hash: HashKey("k"), Role: RoleWrite}})}
+	chain := Chain{&JWTAuthenticator{}, keys}
+
+	p, err := chain.Authenticate(bearer("k"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", p.Name)
+
+	_, err = chain.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestKeySet_RefetchThrottle(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	var mu sync.Mutex
+	fetches, down := 0, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches++
+		failing := down
+		mu.Unlock()
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{rsaJWK("rsa-1", &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	keys, err := NewKeySet(context.Background(), server.URL)
+	assert.NoError(t, err)
+	mu.Lock()
+	down = true
+	mu.Unlock()
+	keys.mu.Lock()
+	keys.attemptedAt = time.Now().Add(-2 * minRefetchInterval)
+	keys.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok := keys.Key(context.Background(), "unknown")
+			assert.False(t, ok)
+		}()
+	}
+	wg.Wait()
+	_, ok := keys.Key(context.Background(), "unknown")
+	assert.False(t, ok)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, fetches, "unknown key IDs trigger one reload, even when it fails")
+	_, ok = keys.Key(context.Background(), "rsa-1")
+	assert.True(t, ok, "the previous keys stay in use")
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// keyPrefix marks strings as API keys issued by this service.
+const keyPrefix = "tgs_"
+
+// APIKey is the stored record of an issued key. The key itself is never stored,
+// only its SHA-256 hash.
+type APIKey struct {
+	ID        int64
+	Name      string
+	Prefix    string // the first characters of the key, to help people recognise it
+	Hash      string
+	Role      Role
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// GenerateKey returns a new random API key.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashKey returns the hex SHA-256 hash under which a key is stored. Keys are
+// long and random, so a fast hash is sufficient.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyStore looks up API keys by hash.
+type KeyStore interface {
+	// LookupHash returns the active key with the given hash, or ErrInvalidCredentials.
+	LookupHash(ctx context.Context, hash string) (APIKey, error)
+}
+
+// APIKeyAuthenticator authenticates requests carrying an API key.
+type APIKeyAuthenticator struct {
+	Store KeyStore
+}
+
+// Authenticate implements Authenticator.
+func (a APIKeyAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	key, ok := credential(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	k, err := a.Store.LookupHash(r.Context(), HashKey(key))
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{ID: fmt.Sprintf("key:%d", k.ID), Name: k.Name, Role: k.Role}, nil
+}
+
+// StaticKeyStore serves keys listed in the configuration file.
+type StaticKeyStore map[string]APIKey
+
+// NewStaticKeyStore indexes configured keys by hash.
+func NewStaticKeyStore(keys []APIKey) StaticKeyStore {
+	store := make(StaticKeyStore, len(keys))
+	for i, k := range keys {
+		if k.ID == 0 {
+			k.ID = int64(-(i + 1)) // Distinguish configured keys from database ones.
+		}
+		store[k.Hash] = k
+	}
+	return store
+}
+
+// LookupHash implements KeyStore.
+func (s StaticKeyStore) LookupHash(_ context.Context, hash string) (APIKey, error) {
+	k, ok := s[hash]
+	if !ok {
+		return APIKey{}, ErrInvalidCredentials
+	}
+	return k, nil
+}
+
+// MultiKeyStore consults several stores in order.
+type MultiKeyStore []KeyStore
+
+// LookupHash implements KeyStore.
+func (m MultiKeyStore) LookupHash(ctx context.Context, hash string) (APIKey, error) {
+	for _, s := range m {
+		k, err := s.LookupHash(ctx, hash)
+		if err == nil || !errors.Is(err, ErrInvalidCredentials) {
+			return k, err
+		}
+	}
+	return APIKey{}, ErrInvalidCredentials
+}
+
+// SQLiteKeyStore keeps API keys in a SQLite database.
+type SQLiteKeyStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteKeyStore opens (creating if needed) the key database at path.
+func OpenSQLiteKeyStore(path string) (*SQLiteKeyStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		hash TEXT NOT NULL UNIQUE,
+		role TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		revoked_at DATETIME
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteKeyStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteKeyStore) Close() error {
+	return s.db.Close()
+}
+
+// Create issues a new key and returns it in clear text. It cannot be recovered later.
+func (s *SQLiteKeyStore) Create(ctx context.Context, name string, role Role) (string, APIKey, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	k := APIKey{Name: name, Prefix: key[:len(keyPrefix)+6], Hash: HashKey(key), Role: role, CreatedAt: time.Now().UTC()}
+	res, err := s.db.ExecContext(ctx, "INSERT INTO api_keys (name, prefix, hash, role, created_at) VALUES (?, ?, ?, ?, ?)",
+		k.Name, k.Prefix, k.Hash, k.Role, k.CreatedAt)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	if k.ID, err = res.LastInsertId(); err != nil {
+		return "", APIKey{}, err
+	}
+	return key, k, nil
+}
+
+// List returns every key, including revoked ones, oldest first.
+func (s *SQLiteKeyStore) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, prefix, hash, role, created_at, revoked_at FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke disables a key. It returns an error if no active key has the ID.
+func (s *SQLiteKeyStore) Revoke(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("no active key with ID %d", id)
+	}
+	return nil
+}
+
+// LookupHash implements KeyStore.
+func (s *SQLiteKeyStore) LookupHash(ctx context.Context, hash string) (APIKey, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT id, name, prefix, hash, role, created_at, revoked_at FROM api_keys "+
+		"WHERE hash = ? AND revoked_at IS NULL", hash)
+	k, err := scanKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrInvalidCredentials
+	}
+	return k, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (APIKey, error) {
+	var k APIKey
+	var revokedAt sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.Role, &k.CreatedAt, &revokedAt); err != nil {
+		return APIKey{}, err
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, nil
+}
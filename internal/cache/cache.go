@@ -0,0 +1,151 @@
+// Package cache keeps the results of repository calls, and of statistics
+// computed from them, in memory until the dataset changes.
+package cache
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Config bounds a cache.
+type Config struct {
+	// TTL is how long an entry is kept, or zero to keep it until evicted.
+	TTL time.Duration
+	// MaxEntries is the most entries kept; the least recently used go first.
+	MaxEntries int
+}
+
+// DefaultMaxEntries bounds a cache whose Config sets no MaxEntries.
+const DefaultMaxEntries = 1000
+
+// Stats counts the lookups of a cache.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+// Cache is a size-bounded LRU cache whose entries expire after a TTL.
+// Concurrent misses for the same key are loaded once.
+type Cache[V any] struct {
+	cfg   Config
+	now   func() time.Time
+	group singleflight.Group
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // Front is the most recently used.
+	generation uint64     // Incremented by Purge.
+	stats      Stats
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// New returns an empty cache.
+func New[V any](cfg Config) *Cache[V] {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	return &Cache[V]{cfg: cfg, now: time.Now, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Get returns the value stored under key, if it has not expired.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[V])
+		if e.expires.IsZero() || c.now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, true
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// GetOrLoad returns the value stored under key, or calls load and stores its
+// result. Callers missing the same key at the same time share one call to
+// load. Errors are returned to every waiting caller but not stored.
+func (c *Cache[V]) GetOrLoad(key string, load func() (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	// Loads started before a Purge are not shared with callers arriving after it.
+	v, err, _ := c.group.Do(strconv.FormatUint(generation, 10)+"\x00"+key, func() (any, error) {
+		v, err := load()
+		if err == nil {
+			c.set(key, v, generation)
+		}
+		return v, err
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return v.(V), nil
+}
+
+// set stores value unless the cache was purged since generation, in which
+// case the value may predate the change that caused the purge.
+func (c *Cache[V]) set(key string, value V, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	var expires time.Time
+	if c.cfg.TTL > 0 {
+		expires = c.now().Add(c.cfg.TTL)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = &entry[V]{key: key, value: value, expires: expires}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry[V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.cfg.MaxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove must be called with c.mu held.
+func (c *Cache[V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry[V]).key)
+}
+
+// Purge drops every entry, and the results of loads still in progress.
+func (c *Cache[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.generation++
+}
+
+// Stats returns the lookup counters and the current number of entries.
+func (c *Cache[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
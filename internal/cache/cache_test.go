@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheLRU(t *testing.T) {
+	c := New[int](Config{MaxEntries: 2})
+	load := func(v int) func() (int, error) { return func() (int, error) { return v, nil } }
+
+	for key, v := range map[string]int{"a": 1, "b": 2} {
+		got, err := c.GetOrLoad(key, load(v))
+		assert.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+	_, ok := c.Get("a") // a is now the most recently used.
+	assert.True(t, ok)
+	_, err := c.GetOrLoad("c", load(3))
+	assert.NoError(t, err)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok, "the least recently used entry is evicted")
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, uint64(2), stats.Hits)
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := New[string](Config{TTL: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	_, err := c.GetOrLoad("k", func() (string, error) { return "old", nil })
+	assert.NoError(t, err)
+	now = now.Add(59 * time.Second)
+	v, ok := c.Get("k")
+	assert.True(t, ok)
+	assert.Equal(t, "old", v)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("k")
+	assert.False(t, ok)
+	assert.Zero(t, c.Stats().Entries)
+}
+
+func TestCacheErrorsAreNotStored(t *testing.T) {
+	c := New[int](Config{})
+	_, err := c.GetOrLoad("k", func() (int, error) { return 0, errors.New("database is locked") })
+	assert.Error(t, err)
+	v, err := c.GetOrLoad("k", func() (int, error) { return 7, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 7, v)
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	c := New[int](Config{})
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad("k", load)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, v)
+		}()
+	}
+	// Give the goroutines time to join the load in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestCachePurgeDropsLoadsInFlight(t *testing.T) {
+	c := New[string](Config{})
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := c.GetOrLoad("k", func() (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "stale", v, "the caller still gets the value it waited for")
+	}()
+
+	<-started
+	c.Purge()
+	v, err := c.GetOrLoad("k", func() (string, error) { return "fresh", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", v, "a load started before the purge is not shared")
+	close(release)
+	<-done
+
+	v, ok := c.Get("k")
+	assert.True(t, ok)
+	assert.Equal(t, "fresh", v, "nor is its result stored")
+}
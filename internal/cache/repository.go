@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// Repository caches the results of the repository it wraps. The cache is
+// emptied whenever the dataset hash reported by Version changes, which covers
+// reloaded files and writes by other processes, and after every import through
+// it. Passengers is not cached, since its callers stream large results.
+//
+// Cached slices are copied on return, so callers may modify them.
+type Repository struct {
+	next  data.PassengerRepository
+	cache *Cache[any]
+
+	mu   sync.Mutex
+	hash string // Dataset hash the cached entries were read from.
+}
+
+// NewRepository wraps repo with a cache bounded by cfg.
+func NewRepository(repo data.PassengerRepository, cfg Config) *Repository {
+	return &Repository{next: repo, cache: New[any](cfg)}
+}
+
+// Stats returns the lookup counters of the cache.
+func (r *Repository) Stats() Stats {
+	return r.cache.Stats()
+}
+
+// Invalidate drops every cached result.
+func (r *Repository) Invalidate() {
+	r.mu.Lock()
+	r.hash = ""
+	r.mu.Unlock()
+	r.cache.Purge()
+}
+
+// current empties the cache if the dataset has changed since it was filled.
+// It returns false if the dataset version cannot be read, in which case the
+// cache must be bypassed.
+func (r *Repository) current(ctx context.Context) bool {
+	version, err := r.next.Version(ctx)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if version.Hash != r.hash {
+		r.cache.Purge()
+		r.hash = version.Hash
+	}
+	return true
+}
+
+// Compute returns the result of compute for key, caching it until the dataset
+// changes like the results of repository calls. It suits statistics derived
+// from the dataset. The result is shared between callers and must not be modified.
+func Compute[T any](ctx context.Context, r *Repository, key string, compute func(ctx context.Context) (T, error)) (T, error) {
+	return load(ctx, r, "compute:"+key, compute)
+}
+
+// load returns the cached result for key or calls fn to fill it.
+func load[T any](ctx context.Context, r *Repository, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	if !r.current(ctx) {
+		return fn(ctx)
+	}
+	v, err := r.cache.GetOrLoad(key, func() (any, error) {
+		// The result goes to every request that missed key meanwhile, not just
+		// to the one that triggered the load.
+		return fn(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+func (r *Repository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	passengers, err := load(ctx, r, "all", r.next.GetAllPassengers)
+	return slices.Clone(passengers), err
+}
+
+// GetPassengerByID caches passengers that do not exist as well, so that
+// lookups of unknown IDs do not all reach the storage.
+func (r *Repository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	p, err := load(ctx, r, "id:"+strconv.Itoa(id), func(ctx context.Context) (*model.Passenger, error) {
+		p, err := r.next.GetPassengerByID(ctx, id)
+		if errors.Is(err, data.ErrPassengerNotFound) {
+			return nil, nil
+		}
+		return p, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, data.ErrPassengerNotFound
+	}
+	found := *p
+	return &found, nil
+}
+
+func (r *Repository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	var key strings.Builder
+	key.WriteString("ids:")
+	for i, id := range ids {
+		if i > 0 {
+			key.WriteByte(',')
+		}
+		key.WriteString(strconv.Itoa(id))
+	}
+	passengers, err := load(ctx, r, key.String(), func(ctx context.Context) ([]model.Passenger, error) {
+		return r.next.GetPassengersByIDs(ctx, ids)
+	})
+	return slices.Clone(passengers), err
+}
+
+func (r *Repository) GetFares(ctx context.Context) ([]float64, error) {
+	fares, err := load(ctx, r, "fares", r.next.GetFares)
+	return slices.Clone(fares), err
+}
+
+func (r *Repository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return r.next.Passengers(ctx)
+}
+
+func (r *Repository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	return r.next.Version(ctx)
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.next.Ping(ctx)
+}
+
+func (r *Repository) Close() error {
+	return r.next.Close()
+}
+
+// Import forwards to the wrapped repository, if it supports imports, and
+// empties the cache afterwards, even if the import failed part way.
+func (r *Repository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	importer, ok := r.next.(data.Importer)
+	if !ok {
+		return model.ImportSummary{}, data.ErrImportNotSupported
+	}
+	defer r.Invalidate()
+	return importer.Import(ctx, mode, records)
+}
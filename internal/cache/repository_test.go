@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRepository embeds the interface so only the methods under test need
+// bodies, and counts the calls that reach it.
+type countingRepository struct {
+	data.PassengerRepository
+
+	mu    sync.Mutex
+	calls map[string]int
+	hash  string
+	fares []float64
+}
+
+func (r *countingRepository) count(op string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+	r.calls[op]++
+}
+
+func (r *countingRepository) callCount(op string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[op]
+}
+
+func (r *countingRepository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hash == "" {
+		return data.DatasetVersion{}, errors.New("file missing")
+	}
+	return data.DatasetVersion{Hash: r.hash}, nil
+}
+
+func (r *countingRepository) GetFares(ctx context.Context) ([]float64, error) {
+	r.count("GetFares")
+	return append([]float64(nil), r.fares...), nil
+}
+
+func (r *countingRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	r.count("GetPassengerByID")
+	if id != 1 {
+		return nil, data.ErrPassengerNotFound
+	}
+	return &model.Passenger{PassengerID: 1, Name: "John Doe"}, nil
+}
+
+func (r *countingRepository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	r.count("Import")
+	return model.ImportSummary{Inserted: len(records)}, nil
+}
+
+func TestRepository(t *testing.T) {
+	next := &countingRepository{hash: "v1", fares: []float64{3, 1, 2}}
+	repo := NewRepository(next, Config{})
+	ctx := context.Background()
+
+	for range 3 {
+		fares, err := repo.GetFares(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []float64{3, 1, 2}, fares)
+		fares[0] = 99 // Callers may sort or modify their copy.
+	}
+	assert.Equal(t, 1, next.callCount("GetFares"))
+
+	for range 2 {
+		p, err := repo.GetPassengerByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "John Doe", p.Name)
+		_, err = repo.GetPassengerByID(ctx, 2)
+		assert.ErrorIs(t, err, data.ErrPassengerNotFound)
+	}
+	assert.Equal(t, 2, next.callCount("GetPassengerByID"), "unknown IDs are cached too")
+
+	// A new dataset version empties the cache.
+	next.mu.Lock()
+	next.hash, next.fares = "v2", []float64{5}
+	next.mu.Unlock()
+	fares, err := repo.GetFares(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{5}, fares)
+	assert.Equal(t, 2, next.callCount("GetFares"))
+
+	// So does an import.
+	_, err = repo.Import(ctx, data.ImportUpsert, []data.ImportRecord{{}})
+	assert.NoError(t, err)
+	_, err = repo.GetFares(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, next.callCount("GetFares"))
+
+	// Without a dataset version the cache is bypassed.
+	next.mu.Lock()
+	next.hash = ""
+	next.mu.Unlock()
+	_, err = repo.GetFares(ctx)
+	assert.NoError(t, err)
+	_, err = repo.GetFares(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, next.callCount("GetFares"))
+
+	stats := repo.Stats()
+	assert.Equal(t, uint64(4), stats.Hits, "two repeated fares lookups and two passenger lookups")
+}
+
+func TestCompute(t *testing.T) {
+	next := &countingRepository{hash: "v1"}
+	repo := NewRepository(next, Config{})
+	computed := 0
+	compute := func(ctx context.Context) (int, error) {
+		computed++
+		return computed, nil
+	}
+
+	for range 2 {
+		v, err := Compute(context.Background(), repo, "stat", compute)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+	repo.Invalidate()
+	v, err := Compute(context.Background(), repo, "stat", compute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestRepositoryImportNotSupported(t *testing.T) {
+	repo := NewRepository(struct{ data.PassengerRepository }{}, Config{})
+	_, err := repo.Import(context.Background(), data.ImportUpsert, nil)
+	assert.ErrorIs(t, err, data.ErrImportNotSupported)
+}
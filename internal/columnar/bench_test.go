@@ -0,0 +1,64 @@
+package columnar
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// benchmarkPassengers returns the embedded dataset repeated to about 90,000
+// passengers, with distinct IDs.
+func benchmarkPassengers(b *testing.B) []model.Passenger {
+	repo, err := data.NewEmbeddedRepository()
+	if err != nil {
+		b.Fatal(err)
+	}
+	titanic, err := repo.GetAllPassengers(context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
+	passengers := make([]model.Passenger, 0, 100*len(titanic))
+	for n := range 100 {
+		for _, p := range titanic {
+			p.PassengerID += n * len(titanic)
+			passengers = append(passengers, p)
+		}
+	}
+	return passengers
+}
+
+// The Struct benchmarks scan a []model.Passenger as the handlers did before
+// the columnar store; the Columnar ones compute the same result from a Table.
+
+func BenchmarkFares(b *testing.B) {
+	passengers := benchmarkPassengers(b)
+	table := FromPassengers(passengers)
+
+	b.Run("Struct", func(b *testing.B) {
+		for b.Loop() {
+			var fares []float64
+			for i := range passengers {
+				if passengers[i].Fare != nil {
+					fares = append(fares, *passengers[i].Fare)
+				}
+			}
+			slices.Sort(fares)
+		}
+	})
+	b.Run("Columnar", func(b *testing.B) {
+		for b.Loop() {
+			fares := table.Fare.Select(table.Fare.Valid)
+			slices.Sort(fares)
+		}
+	})
+}
+
+func BenchmarkBuild(b *testing.B) {
+	passengers := benchmarkPassengers(b)
+	for b.Loop() {
+		FromPassengers(passengers)
+	}
+}
@@ -0,0 +1,78 @@
+package columnar
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// Bitmap is a set of row numbers, one bit per row. Bitmaps of the same table
+// all cover its rows, so they can be combined word by word.
+type Bitmap []uint64
+
+// NewBitmap returns an empty bitmap for n rows.
+func NewBitmap(n int) Bitmap {
+	return make(Bitmap, (n+63)/64)
+}
+
+// Set adds row i, growing the bitmap if needed.
+func (b *Bitmap) Set(i int) {
+	for i/64 >= len(*b) {
+		*b = append(*b, 0)
+	}
+	(*b)[i/64] |= 1 << (i % 64)
+}
+
+// Has reports whether row i is in the bitmap.
+func (b Bitmap) Has(i int) bool {
+	return i/64 < len(b) && b[i/64]&(1<<(i%64)) != 0
+}
+
+// Count returns the number of rows in the bitmap.
+func (b Bitmap) Count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Rows yields the rows in the bitmap in ascending order.
+func (b Bitmap) Rows() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i, w := range b {
+			for w != 0 {
+				if !yield(i*64 + bits.TrailingZeros64(w)) {
+					return
+				}
+				w &= w - 1
+			}
+		}
+	}
+}
+
+// AndCount returns the number of rows held by every one of the bitmaps,
+// without building their intersection.
+func AndCount(bitmaps ...Bitmap) int {
+	if len(bitmaps) == 0 {
+		return 0
+	}
+	n := 0
+	for i, w := range bitmaps[0] {
+		for _, other := range bitmaps[1:] {
+			if i >= len(other) {
+				w = 0
+				break
+			}
+			w &= other[i]
+		}
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// grow pads b with empty words so that it covers n rows.
+func (b *Bitmap) grow(n int) {
+	for len(*b) < (n+63)/64 {
+		*b = append(*b, 0)
+	}
+}
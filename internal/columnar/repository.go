@@ -0,0 +1,105 @@
+package columnar
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// Repository keeps a columnar copy of the dataset of the repository it wraps,
+// rebuilt whenever the dataset hash reported by Version changes and after
+// every import through it. Fares are served from the copy; the other calls go
+// to the wrapped repository.
+type Repository struct {
+	next data.PassengerRepository
+
+	mu    sync.Mutex
+	hash  string // Dataset hash the table was built from.
+	table *Table
+}
+
+// NewRepository wraps repo with a columnar copy of its dataset, built on first use.
+func NewRepository(repo data.PassengerRepository) *Repository {
+	return &Repository{next: repo}
+}
+
+// Table returns the columnar copy of the current dataset, building it first if
+// the dataset has changed. Callers wait while it is built.
+func (r *Repository) Table(ctx context.Context) (*Table, error) {
+	version, err := r.next.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.table != nil && version.Hash == r.hash {
+		return r.table, nil
+	}
+	// The table serves every request until the dataset changes, so a client
+	// disconnecting halfway must not throw the build away.
+	table, err := Build(r.next.Passengers(context.WithoutCancel(ctx)))
+	if err != nil {
+		return nil, err
+	}
+	r.hash, r.table = version.Hash, table
+	return table, nil
+}
+
+// Invalidate drops the columnar copy, so that the next call rebuilds it.
+func (r *Repository) Invalidate() {
+	r.mu.Lock()
+	r.hash, r.table = "", nil
+	r.mu.Unlock()
+}
+
+func (r *Repository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	return r.next.GetAllPassengers(ctx)
+}
+
+func (r *Repository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	return r.next.GetPassengerByID(ctx, id)
+}
+
+func (r *Repository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	return r.next.GetPassengersByIDs(ctx, ids)
+}
+
+// GetFares returns the fares of the columnar copy, in row order like the
+// wrapped repository.
+func (r *Repository) GetFares(ctx context.Context) ([]float64, error) {
+	table, err := r.Table(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return table.Fare.Select(table.Fare.Valid), nil
+}
+
+func (r *Repository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return r.next.Passengers(ctx)
+}
+
+func (r *Repository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	return r.next.Version(ctx)
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.next.Ping(ctx)
+}
+
+func (r *Repository) Close() error {
+	return r.next.Close()
+}
+
+// Import forwards to the wrapped repository, if it supports imports, and drops
+// the columnar copy afterwards, even if the import failed part way.
+func (r *Repository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	importer, ok := r.next.(data.Importer)
+	if !ok {
+		return model.ImportSummary{}, data.ErrImportNotSupported
+	}
+	defer r.Invalidate()
+	return importer.Import(ctx, mode, records)
+}
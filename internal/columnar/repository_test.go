@@ -0,0 +1,108 @@
+package columnar
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRepository embeds the interface so only the methods under test need
+// bodies, and counts the full scans that reach it.
+type countingRepository struct {
+	data.PassengerRepository
+
+	mu         sync.Mutex
+	scans      int
+	hash       string
+	passengers []model.Passenger
+}
+
+func (r *countingRepository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hash == "" {
+		return data.DatasetVersion{}, errors.New("file missing")
+	}
+	return data.DatasetVersion{Hash: r.hash}, nil
+}
+
+func (r *countingRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	r.mu.Lock()
+	r.scans++
+	passengers := r.passengers
+	r.mu.Unlock()
+	return func(yield func(model.Passenger, error) bool) {
+		for _, p := range passengers {
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *countingRepository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	return model.ImportSummary{Inserted: len(records)}, nil
+}
+
+func (r *countingRepository) update(hash string, passengers []model.Passenger) {
+	r.mu.Lock()
+	r.hash, r.passengers = hash, passengers
+	r.mu.Unlock()
+}
+
+func TestRepository(t *testing.T) {
+	next := &countingRepository{hash: "v1", passengers: testPassengers()}
+	repo := NewRepository(next)
+	ctx := context.Background()
+
+	for range 3 {
+		fares, err := repo.GetFares(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []float64{7.25, 71.2833, 7.925, 80}, fares, "fares keep the order of the rows")
+		fares[0] = 99 // Callers may sort or modify their copy.
+	}
+	table, err := repo.Table(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, table.Len())
+	assert.Equal(t, 1, next.scans, "the table is built once per dataset version")
+
+	next.update("v2", testPassengers()[:2])
+	table, err = repo.Table(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, table.Len())
+	assert.Equal(t, 2, next.scans)
+
+	_, err = repo.Import(ctx, data.ImportUpsert, nil)
+	assert.NoError(t, err)
+	_, err = repo.Table(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, next.scans, "an import drops the table")
+
+	next.update("", nil)
+	_, err = repo.GetFares(ctx)
+	assert.EqualError(t, err, "file missing")
+}
+
+func TestRepositoryConcurrentBuild(t *testing.T) {
+	next := &countingRepository{hash: "v1", passengers: testPassengers()}
+	repo := NewRepository(next)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			table, err := repo.Table(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, 5, table.Len())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, next.scans)
+}
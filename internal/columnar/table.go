@@ -0,0 +1,87 @@
+// Package columnar holds a dataset in memory column by column: a typed slice
+// per field and a bitmap marking the rows where the value is set, so that
+// statistics read a slice instead of walking passenger structs. Only the fares
+// are kept, as they are the only column the service reads.
+package columnar
+
+import (
+	"iter"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// Float64s is a numeric column with missing values.
+type Float64s struct {
+	// Values holds each row's value, or zero where the value is missing.
+	Values []float64
+	// Valid holds the rows whose value is set.
+	Valid Bitmap
+}
+
+// Select returns the values set in the given rows, in row order.
+func (c *Float64s) Select(rows Bitmap) []float64 {
+	values := make([]float64, 0, AndCount(rows, c.Valid))
+	for i := range rows.Rows() {
+		if c.Valid.Has(i) {
+			values = append(values, c.Values[i])
+		}
+	}
+	return values
+}
+
+// Table is a dataset stored by column. Row i of every column belongs to the
+// same passenger, in the order the passengers were read. A Table is immutable
+// once built and safe for concurrent use.
+type Table struct {
+	n int
+
+	Fare Float64s
+}
+
+// Len returns the number of rows.
+func (t *Table) Len() int {
+	return t.n
+}
+
+// Build reads every passenger of seq into a new Table. It stops at the first error.
+func Build(seq iter.Seq2[model.Passenger, error]) (*Table, error) {
+	t := &Table{}
+	for p, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		t.add(&p)
+	}
+	return t.finish(), nil
+}
+
+// FromPassengers builds a Table holding passengers.
+func FromPassengers(passengers []model.Passenger) *Table {
+	t := &Table{}
+	for i := range passengers {
+		t.add(&passengers[i])
+	}
+	return t.finish()
+}
+
+func (t *Table) add(p *model.Passenger) {
+	i := t.n
+	t.n++
+	addFloat(&t.Fare, i, p.Fare)
+}
+
+func addFloat(c *Float64s, i int, v *float64) {
+	if v == nil {
+		c.Values = append(c.Values, 0)
+		return
+	}
+	c.Values = append(c.Values, *v)
+	c.Valid.Set(i)
+}
+
+// finish sizes every bitmap to the number of rows, so that bitmaps of the
+// table can be combined word by word.
+func (t *Table) finish() *Table {
+	t.Fare.Valid.grow(t.n)
+	return t
+}
@@ -0,0 +1,73 @@
+package columnar
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testPassengers() []model.Passenger {
+	return []model.Passenger{
+		{PassengerID: 1, Survived: 0, Pclass: 3, Name: "Braund, Mr. Owen Harris", Sex: "male", Age: ptr(22.0), SibSp: 1, Ticket: "A/5 21171", Fare: ptr(7.25), Embarked: ptr("S")},
+		{PassengerID: 2, Survived: 1, Pclass: 1, Name: "Cumings, Mrs. John Bradley", Sex: "female", Age: ptr(38.0), SibSp: 1, Ticket: "PC 17599", Fare: ptr(71.2833), Cabin: ptr("C85"), Embarked: ptr("C")},
+		{PassengerID: 3, Survived: 1, Pclass: 3, Name: "Heikkinen, Miss. Laina", Sex: "female", Ticket: "STON/O2. 3101282", Fare: ptr(7.925), Embarked: ptr("S")},
+		{PassengerID: 62, Survived: 1, Pclass: 1, Name: "Icard, Miss. Amelie", Sex: "female", Age: ptr(38.0), Ticket: "113572", Fare: ptr(80.0), Cabin: ptr("B28")},
+		{PassengerID: 5, Survived: 0, Pclass: 3, Name: "Allen, Mr. William Henry", Sex: "male", Age: ptr(35.0), Ticket: "373450", Embarked: ptr("S")},
+	}
+}
+
+func TestBitmap(t *testing.T) {
+	b := NewBitmap(70)
+	b.Set(0)
+	b.Set(64)
+	b.Set(69)
+	assert.True(t, b.Has(64))
+	assert.False(t, b.Has(1))
+	assert.False(t, b.Has(1000))
+	assert.Equal(t, 3, b.Count())
+	assert.Equal(t, []int{0, 64, 69}, slices.Collect(b.Rows()))
+
+	other := NewBitmap(70)
+	other.Set(64)
+	other.Set(65)
+	assert.Equal(t, 1, AndCount(b, other))
+	assert.Equal(t, 3, AndCount(b, b))
+
+	var grown Bitmap
+	grown.Set(130)
+	assert.Len(t, grown, 3)
+	assert.Equal(t, 0, AndCount(b, nil))
+}
+
+func TestBuild(t *testing.T) {
+	passengers := testPassengers()
+	table := FromPassengers(passengers)
+
+	assert.Equal(t, len(passengers), table.Len())
+	assert.Equal(t, []float64{7.25, 71.2833, 7.925, 80, 0}, table.Fare.Values)
+	assert.Equal(t, 4, table.Fare.Valid.Count())
+	assert.Len(t, table.Fare.Valid, 1, "bitmaps cover every row")
+	assert.Equal(t, []float64{7.25, 71.2833, 7.925, 80}, table.Fare.Select(table.Fare.Valid), "missing values are skipped")
+	some := NewBitmap(table.Len())
+	some.Set(1)
+	some.Set(4)
+	assert.Equal(t, []float64{71.2833}, table.Fare.Select(some))
+}
+
+func TestBuildError(t *testing.T) {
+	seq := iter.Seq2[model.Passenger, error](func(yield func(model.Passenger, error) bool) {
+		if yield(testPassengers()[0], nil) {
+			yield(model.Passenger{}, errors.New("disk on fire"))
+		}
+	})
+	_, err := Build(seq)
+	assert.EqualError(t, err, "disk on fire")
+}
@@ -1,24 +1,143 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	Server struct {
-		Port string `mapstructure:"port"`
+		Port              string        `mapstructure:"port"`
+		ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+		ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+		// WriteTimeout bounds the whole response, so it must allow for the largest export.
+		WriteTimeout time.Duration `mapstructure:"write_timeout"`
+		IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+		// ShutdownTimeout is how long in-flight requests may run after SIGTERM.
+		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+		// TrustedProxies lists the addresses or CIDR ranges of the proxies whose
+		// X-Forwarded-For header gives the client IP. Empty trusts none.
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"server"`
+	Log struct {
+		// Level is "debug", "info", "warn" or "error".
+		Level string `mapstructure:"level"`
+	} `mapstructure:"log"`
+	Metrics struct {
+		Enabled bool `mapstructure:"enabled"`
+		// Port serves /metrics apart from the API, so it can stay internal.
+		Port string `mapstructure:"port"`
+	} `mapstructure:"metrics"`
+	Tracing struct {
+		// Exporter is "none", "stdout" or "otlp".
+		Exporter    string `mapstructure:"exporter"`
+		ServiceName string `mapstructure:"service_name"`
+		// Endpoint is the OTLP/HTTP collector address, e.g. "localhost:4318".
+		Endpoint string `mapstructure:"endpoint"`
+		Insecure bool   `mapstructure:"insecure"`
+		// SampleRatio is the fraction of new traces that are recorded.
+		SampleRatio float64 `mapstructure:"sample_ratio"`
+	} `mapstructure:"tracing"`
+	TLS struct {
+		Enabled  bool   `mapstructure:"enabled"`
+		CertFile string `mapstructure:"cert_file"`
+		KeyFile  string `mapstructure:"key_file"`
+		// ReloadInterval is how often the files are checked for rotation.
+		ReloadInterval time.Duration `mapstructure:"reload_interval"`
+		// ClientAuth is "none", "optional" or "require". The other modes verify
+		// client certificates against ClientCAFile.
+		ClientAuth   string `mapstructure:"client_auth"`
+		ClientCAFile string `mapstructure:"client_ca_file"`
+	} `mapstructure:"tls"`
 	Data struct {
-		Source  string `mapstructure:"source"`
-		CSVFile string `mapstructure:"csv_file"`
-		DBFile  string `mapstructure:"db_file"`
+		// Source names the registered data source to serve. Its settings are in
+		// the section of the same name, read with DecodeDataSource.
+		Source string `mapstructure:"source"`
+		// Fallback, if set, names a second data source that serves while Source fails.
+		Fallback string `mapstructure:"fallback"`
+		Failover struct {
+			// FailureThreshold consecutive failures of Source send every call to
+			// Fallback for OpenTimeout, after which Source is tried again.
+			FailureThreshold int           `mapstructure:"failure_threshold"`
+			OpenTimeout      time.Duration `mapstructure:"open_timeout"`
+			// Shadow repeats reads served by Source on Fallback and logs differences.
+			Shadow        bool          `mapstructure:"shadow"`
+			ShadowTimeout time.Duration `mapstructure:"shadow_timeout"`
+		} `mapstructure:"failover"`
 	} `mapstructure:"data"`
+	Cache struct {
+		// Enabled keeps repository results and computed statistics in memory
+		// until the dataset changes, TTL passes or MaxEntries pushes them out.
+		Enabled    bool          `mapstructure:"enabled"`
+		TTL        time.Duration `mapstructure:"ttl"`
+		MaxEntries int           `mapstructure:"max_entries"`
+	} `mapstructure:"cache"`
+	Columnar struct {
+		// Enabled keeps the fares in memory as a column, from which the fare
+		// histogram is computed, rebuilt when the dataset changes.
+		Enabled bool `mapstructure:"enabled"`
+	} `mapstructure:"columnar"`
+	HTTP struct {
+		// CacheControl maps a route group ("passengers", "stats", "export") to the
+		// Cache-Control header sent with its responses.
+		CacheControl map[string]string `mapstructure:"cache_control"`
+		// BatchGetLimit is the maximum number of IDs in one batch lookup.
+		BatchGetLimit int `mapstructure:"batch_get_limit"`
+	} `mapstructure:"http"`
+	Jobs struct {
+		Enabled bool `mapstructure:"enabled"`
+		// DBFile is the SQLite database holding job state across restarts.
+		DBFile    string `mapstructure:"db_file"`
+		ResultDir string `mapstructure:"result_dir"`
+		Workers   int    `mapstructure:"workers"`
+		QueueSize int    `mapstructure:"queue_size"`
+		// ResultTTL is how long finished jobs and their results are kept, e.g. "24h".
+		ResultTTL time.Duration `mapstructure:"result_ttl"`
+	} `mapstructure:"jobs"`
+	RateLimit struct {
+		Enabled bool `mapstructure:"enabled"`
+		// Groups maps a route group ("passengers", "stats", "export", "jobs", "admin") to its limit.
+		Groups map[string]RateLimit `mapstructure:"groups"`
+		// DailyQuota is the number of requests each client may make per UTC day. 0 means unlimited.
+		DailyQuota int `mapstructure:"daily_quota"`
+		// QuotaDB, when set, persists quota usage in a SQLite database instead of memory.
+		QuotaDB string `mapstructure:"quota_db"`
+	} `mapstructure:"rate_limit"`
+	Auth struct {
+		// Enabled requires an API key on every route. When disabled the admin API is unavailable.
+		Enabled bool `mapstructure:"enabled"`
+		// KeysDB is the SQLite database of keys managed with cmd/keys.
+		KeysDB string `mapstructure:"keys_db"`
+		// Keys are additional keys defined in the configuration, stored by hash.
+		Keys []APIKey `mapstructure:"keys"`
+		JWT  JWT      `mapstructure:"jwt"`
+		// ClientCerts grants roles to mTLS clients by certificate common name.
+		ClientCerts []ClientCert `mapstructure:"client_certs"`
+	} `mapstructure:"auth"`
+}
+
+// RateLimit is a token bucket refilled at Rate requests per second up to Burst.
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// APIKey is a key configured statically. Hash is the hex SHA-256 of the key,
+// as printed by "keys hash".
+type APIKey struct {
+	Name string `mapstructure:"name"`
+	Role string `mapstructure:"role"`
+	Hash string `mapstructure:"hash"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
+	// Nested keys can be overridden from the environment, e.g. AUTH_ENABLED for auth.enabled.
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
@@ -29,3 +148,39 @@ func LoadConfig(path string) (config Config, err error) {
 	err = viper.Unmarshal(&config)
 	return
 }
+
+// DecodeDataSource decodes the data.<name> section, the settings of the named
+// data source, into v. It must be called after LoadConfig.
+func DecodeDataSource(name string, v any) error {
+	// UnmarshalKey would miss environment overrides of the section's keys, so
+	// the section is copied key by key into a viper of its own.
+	prefix := "data." + name + "."
+	section := viper.New()
+	for _, key := range viper.AllKeys() {
+		if strings.HasPrefix(key, prefix) {
+			section.Set(strings.TrimPrefix(key, prefix), viper.Get(key))
+		}
+	}
+	return section.Unmarshal(v)
+}
+
+// ClientCert grants a role to clients whose verified certificate has the common name.
+type ClientCert struct {
+	CommonName string `mapstructure:"common_name"`
+	Role       string `mapstructure:"role"`
+}
+
+// JWT configures validation of bearer JWTs issued by an OIDC provider.
+type JWT struct {
+	Enabled bool `mapstructure:"enabled"`
+	// JWKS is the path or URL of the issuer's JSON Web Key Set.
+	JWKS            string        `mapstructure:"jwks"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	Issuer          string        `mapstructure:"issuer"`
+	Audience        string        `mapstructure:"audience"`
+	Leeway          time.Duration `mapstructure:"leeway"`
+	// RoleClaim is the claim holding the caller's roles or scopes.
+	RoleClaim string `mapstructure:"role_claim"`
+	// Roles maps each service role ("read", "write", "admin") to the claim values granting it.
+	Roles map[string][]string `mapstructure:"roles"`
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// CompareRepositories reads every passenger from both backends and reports the
+// passengers found in only one of them and the fields that differ between the
+// others. At most maxDifferences field differences are listed, or all of them
+// if maxDifferences is not positive.
+func CompareRepositories(ctx context.Context, left, right Backend, maxDifferences int) (model.ConsistencyReport, error) {
+	report := model.ConsistencyReport{
+		Left:         left.Name,
+		Right:        right.Name,
+		OnlyInLeft:   []int{},
+		OnlyInRight:  []int{},
+		DuplicateIDs: []int{},
+		Differences:  []model.FieldDifference{},
+	}
+	duplicates := make(map[int]bool)
+
+	leftByID, err := passengersByID(ctx, left, duplicates)
+	if err != nil {
+		return report, err
+	}
+	rightByID, err := passengersByID(ctx, right, duplicates)
+	if err != nil {
+		return report, err
+	}
+	report.LeftRows, report.RightRows = len(leftByID), len(rightByID)
+	for _, side := range []struct {
+		backend Backend
+		invalid *int
+	}{{left, &report.LeftInvalid}, {right, &report.RightInvalid}} {
+		version, err := side.backend.Repo.Version(ctx)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", side.backend.Name, err)
+		}
+		*side.invalid = version.Invalid
+	}
+
+	ids := make([]int, 0, len(leftByID))
+	for id := range leftByID {
+		ids = append(ids, id)
+	}
+	for id := range rightByID {
+		if _, ok := leftByID[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		l, inLeft := leftByID[id]
+		r, inRight := rightByID[id]
+		switch {
+		case !inRight:
+			report.OnlyInLeft = append(report.OnlyInLeft, id)
+			continue
+		case !inLeft:
+			report.OnlyInRight = append(report.OnlyInRight, id)
+			continue
+		}
+		diffs := diffFields(l, r)
+		if len(diffs) == 0 {
+			continue
+		}
+		report.Mismatched++
+		for _, d := range diffs {
+			if maxDifferences > 0 && len(report.Differences) == maxDifferences {
+				report.Truncated = true
+				break
+			}
+			report.Differences = append(report.Differences, d)
+		}
+	}
+	for id := range duplicates {
+		report.DuplicateIDs = append(report.DuplicateIDs, id)
+	}
+	slices.Sort(report.DuplicateIDs)
+
+	// A skipped record may hold a passenger the other side lacks or has
+	// differently, so it is a mismatch even when the valid rows agree.
+	report.Consistent = len(report.OnlyInLeft) == 0 && len(report.OnlyInRight) == 0 &&
+		len(report.DuplicateIDs) == 0 && report.Mismatched == 0 &&
+		report.LeftInvalid == 0 && report.RightInvalid == 0
+	return report, nil
+}
+
+// passengersByID reads a backend into a map. IDs seen more than once are
+// added to duplicates, and their first record is kept.
+func passengersByID(ctx context.Context, b Backend, duplicates map[int]bool) (map[int]model.Passenger, error) {
+	byID := make(map[int]model.Passenger)
+	for p, err := range b.Repo.Passengers(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name, err)
+		}
+		if _, ok := byID[p.PassengerID]; ok {
+			duplicates[p.PassengerID] = true
+			continue
+		}
+		byID[p.PassengerID] = p
+	}
+	return byID, nil
+}
+
+// diffFields compares two passengers field by field.
+func diffFields(l, r model.Passenger) []model.FieldDifference {
+	var diffs []model.FieldDifference
+	for _, f := range model.PassengerFields {
+		a, b := formatField(f.Get(&l)), formatField(f.Get(&r))
+		if a != b {
+			diffs = append(diffs, model.FieldDifference{PassengerID: l.PassengerID, Field: f.Name, Left: a, Right: b})
+		}
+	}
+	return diffs
+}
+
+// formatField renders a field value as JSON, so missing values show as null,
+// strings are quoted and floats are printed in full.
+func formatField(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
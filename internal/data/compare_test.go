@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareRepositories(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "titanic.csv")
+	assert.NoError(t, os.WriteFile(csvPath, []byte(httpTestHeader+
+		"1,0,3,Old One,male,22,1,0,A/5,7.25,,S\n"+
+		"2,1,1,Old Two ,female,38,1,0,PC,71.28,C85,C\n"+
+		"4,1,1,Only Csv,female,,0,0,X,1,,S\n"+
+		"4,1,1,Only Csv Again,female,,0,0,X,1,,S\n"+
+		"x,1,1,Invalid,female,,0,0,X,1,,S\n"), 0o644))
+	csvRepo, err := NewCSVRepository(csvPath)
+	assert.NoError(t, err)
+	sqliteRepo := newTempSQLiteRepository(t)
+	_, err = sqliteRepo.db.Exec(`INSERT INTO passengers VALUES (3, 0, 2, 'Only Db', 'male', NULL, 0, 0, 'B', 10, NULL, 'Q')`)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	report, err := CompareRepositories(ctx, Backend{Name: "csv", Repo: csvRepo}, Backend{Name: "sqlite", Repo: sqliteRepo}, 0)
+	assert.NoError(t, err)
+	assert.False(t, report.Consistent)
+	assert.Equal(t, 3, report.LeftRows)
+	assert.Equal(t, 3, report.RightRows)
+	assert.Equal(t, 1, report.LeftInvalid)
+	assert.Equal(t, []int{4}, report.OnlyInLeft)
+	assert.Equal(t, []int{3}, report.OnlyInRight)
+	assert.Equal(t, []int{4}, report.DuplicateIDs)
+	assert.Equal(t, 1, report.Mismatched)
+	assert.Equal(t, []model.FieldDifference{
+		{PassengerID: 2, Field: "name", Left: `"Old Two "`, Right: `"Old Two"`},
+	}, report.Differences)
+
+	report, err = CompareRepositories(ctx, Backend{Name: "sqlite", Repo: sqliteRepo}, Backend{Name: "sqlite", Repo: sqliteRepo}, 0)
+	assert.NoError(t, err)
+	assert.True(t, report.Consistent)
+	assert.Empty(t, report.Differences)
+}
+
+func TestCompareRepositoriesInvalidRecord(t *testing.T) {
+	dir := t.TempDir()
+	rows := httpTestHeader +
+		"1,0,3,Old One,male,22,1,0,A/5,7.25,,S\n" +
+		"2,1,1,Old Two,female,38,1,0,PC,71.28,C85,C\n"
+	clean, dirty := filepath.Join(dir, "clean.csv"), filepath.Join(dir, "dirty.csv")
+	assert.NoError(t, os.WriteFile(clean, []byte(rows), 0o644))
+	assert.NoError(t, os.WriteFile(dirty, []byte(rows+"x,1,1,Bad ID,female,,0,0,X,1,,S\n"), 0o644))
+	cleanRepo, err := NewCSVRepository(clean)
+	assert.NoError(t, err)
+	dirtyRepo, err := NewCSVRepository(dirty)
+	assert.NoError(t, err)
+
+	report, err := CompareRepositories(context.Background(), Backend{Name: "clean", Repo: cleanRepo}, Backend{Name: "dirty", Repo: dirtyRepo}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, report.LeftRows, report.RightRows)
+	assert.Zero(t, report.Mismatched)
+	assert.Equal(t, 1, report.RightInvalid)
+	assert.False(t, report.Consistent, "a skipped record is a mismatch")
+}
+
+func TestCompareRepositoriesLimit(t *testing.T) {
+	left := newTempSQLiteRepository(t)
+	right := newTempSQLiteRepository(t)
+	_, err := right.db.Exec(`UPDATE passengers SET Fare = Fare + 1, Age = NULL`)
+	assert.NoError(t, err)
+
+	report, err := CompareRepositories(context.Background(), Backend{Name: "a", Repo: left}, Backend{Name: "b", Repo: right}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Mismatched)
+	assert.True(t, report.Truncated)
+	assert.Equal(t, []model.FieldDifference{
+		{PassengerID: 1, Field: "age", Left: "22", Right: "null"},
+		{PassengerID: 1, Field: "fare", Left: "7.25", Right: "8.25"},
+		{PassengerID: 2, Field: "age", Left: "38", Right: "null"},
+	}, report.Differences)
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// csvIndex maps passenger IDs to the byte offset of their record in the CSV file,
+// so single records can be read back without scanning or holding the whole file.
+// It is rebuilt whenever the file's stamp changes.
+type csvIndex struct {
+	mu      sync.Mutex
+	stamp   string
+	offsets map[int]int64
+}
+
+// lookup returns the offsets of the given IDs, rebuilding the index with build
+// first if the file's stamp has changed since it was last built. IDs that are
+// not present are skipped.
+func (idx *csvIndex) lookup(stamp string, build func() (map[int]int64, error), ids []int) (map[int]int64, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.offsets == nil || stamp != idx.stamp {
+		offsets, err := build()
+		if err != nil {
+			return nil, err
+		}
+		idx.stamp, idx.offsets = stamp, offsets
+	}
+
+	found := make(map[int]int64, len(ids))
+	for _, id := range ids {
+		if offset, ok := idx.offsets[id]; ok {
+			found[id] = offset
+		}
+	}
+	return found, nil
+}
+
+// buildCSVIndex scans the file once, recording where each record starts.
+func buildCSVIndex(file io.Reader) (map[int]int64, error) {
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+	reader.Read() // Skip the header row
+
+	offsets := make(map[int]int64)
+	for {
+		offset := reader.InputOffset()
+		record, err := reader.Read()
+		if err == io.EOF {
+			return offsets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.Atoi(record[0])
+		if err != nil {
+			// recordToPassenger rejects the same records, so they are never served.
+			continue
+		}
+		if _, dup := offsets[id]; !dup {
+			offsets[id] = offset
+		}
+	}
+}
+
+// readCSVRecordAt reads the single record starting at offset.
+func readCSVRecordAt(file io.ReadSeeker, offset int64) ([]string, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return csv.NewReader(file).Read()
+}
@@ -1,17 +1,43 @@
 package data
 
 import (
+	"context"
 	"encoding/csv"
 	"errors"
+	"fmt"
+	"github.com/dhope-nagesh/titanic-go-service/internal/filestamp"
 	"github.com/dhope-nagesh/titanic-go-service/internal/model"
 	"io"
+	"io/fs"
+	"iter"
+	"log/slog"
 	"os"
 	"strconv"
+	"time"
 )
 
+func init() {
+	RegisterSource("csv", func(cfg CSVConfig) (PassengerRepository, error) {
+		repo, err := NewCSVRepository(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+}
+
+// CSVConfig is the data.csv section of the configuration.
+type CSVConfig struct {
+	File string `mapstructure:"file"`
+}
+
 // CSVRepository holds the path to the CSV file.
 type CSVRepository struct {
 	filePath string
+	// fsys is the file system filePath is read from, or nil for the operating system's.
+	fsys    fs.FS
+	index   csvIndex
+	version versionCache
 }
 
 // NewCSVRepository creates a new instance of the CSV repository.
@@ -23,66 +49,162 @@ func NewCSVRepository(filePath string) (*CSVRepository, error) {
 	return &CSVRepository{filePath: filePath}, nil
 }
 
-// read is a helper function to open, read, and parse the entire CSV file.
-func (r *CSVRepository) read() ([]model.Passenger, error) {
-	file, err := os.Open(r.filePath)
+// NewCSVRepositoryFS creates a CSV repository reading the file name from fsys,
+// such as an embed.FS.
+func NewCSVRepositoryFS(fsys fs.FS, name string) (*CSVRepository, error) {
+	if _, err := fs.Stat(fsys, name); err != nil {
+		return nil, fmt.Errorf("CSV file %s cannot be read: %w", name, err)
+	}
+	return &CSVRepository{filePath: name, fsys: fsys}, nil
+}
+
+// open opens the CSV file for reading.
+func (r *CSVRepository) open() (fs.File, error) {
+	if r.fsys != nil {
+		return r.fsys.Open(r.filePath)
+	}
+	return os.Open(r.filePath)
+}
+
+// stamp identifies the current content of the CSV file; see fileStamp.
+func (r *CSVRepository) stamp() (string, time.Time, error) {
+	if r.fsys == nil {
+		return filestamp.Of(r.filePath)
+	}
+	info, err := fs.Stat(r.fsys, r.filePath)
 	if err != nil {
-		return nil, err
+		return "", time.Time{}, err
 	}
-	defer file.Close()
+	return fmt.Sprintf("%s:%d:%d", r.filePath, info.Size(), info.ModTime().UnixNano()), info.ModTime(), nil
+}
 
-	reader := csv.NewReader(file)
-	reader.Read() // Skip the header row
+// Passengers streams passengers from the CSV file one record at a time.
+func (r *CSVRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return r.passengers(ctx, nil)
+}
 
-	var passengers []model.Passenger
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break // End of file
-		}
+// passengers streams the file, counting the records skipped as invalid in
+// *invalid when it is not nil.
+func (r *CSVRepository) passengers(ctx context.Context, invalid *int) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		file, err := r.open()
 		if err != nil {
-			return nil, err
+			yield(model.Passenger{}, err)
+			return
 		}
+		defer file.Close()
 
-		p, err := recordToPassenger(record)
-		if err != nil {
-			// In a real application, you might want to log this error
-			// instead of stopping the entire process.
-			continue
+		reader := csv.NewReader(file)
+		reader.ReuseRecord = true
+		reader.Read() // Skip the header row
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(model.Passenger{}, err)
+				return
+			}
+			record, err := reader.Read()
+			if err == io.EOF {
+				return // End of file
+			}
+			if err != nil {
+				yield(model.Passenger{}, err)
+				return
+			}
+
+			p, err := recordToPassenger(record)
+			if err != nil {
+				line, _ := reader.FieldPos(0)
+				slog.WarnContext(ctx, "skipping invalid passenger record", "file", r.filePath, "line", line, "error", err)
+				if invalid != nil {
+					*invalid++
+				}
+				continue
+			}
+			if !yield(p, nil) {
+				return
+			}
 		}
-		passengers = append(passengers, p)
 	}
-	return passengers, nil
 }
 
 // GetAllPassengers returns all passengers from the CSV file.
-func (r *CSVRepository) GetAllPassengers() ([]model.Passenger, error) {
-	return r.read()
+func (r *CSVRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	return collect(r.Passengers(ctx))
 }
 
-// GetPassengerByID finds a single passenger by their ID in the CSV file.
-func (r *CSVRepository) GetPassengerByID(id int) (*model.Passenger, error) {
-	passengers, err := r.read()
+// GetPassengerByID finds a single passenger by their ID using the record index.
+func (r *CSVRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	passengers, err := r.GetPassengersByIDs(ctx, []int{id})
 	if err != nil {
 		return nil, err
 	}
-	for _, p := range passengers {
-		if p.PassengerID == id {
-			return &p, nil
-		}
+	if len(passengers) == 0 {
+		return nil, ErrPassengerNotFound
 	}
-	return nil, errors.New("passenger not found")
+	return &passengers[0], nil
 }
 
-// GetFares extracts all valid fare values from the CSV file.
-func (r *CSVRepository) GetFares() ([]float64, error) {
-	passengers, err := r.read()
+// GetPassengersByIDs reads only the requested records, located through the
+// in-memory index of record offsets. Results follow the order of ids.
+func (r *CSVRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	stamp, _, err := r.stamp()
 	if err != nil {
 		return nil, err
 	}
+	offsets, err := r.index.lookup(stamp, func() (map[int]int64, error) {
+		file, err := r.open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return buildCSVIndex(file)
+	}, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(offsets) == 0 {
+		return nil, nil
+	}
 
+	file, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	seeker, ok := file.(io.ReadSeeker)
+	if !ok {
+		return nil, fmt.Errorf("CSV file %s does not support seeking", r.filePath)
+	}
+
+	passengers := make([]model.Passenger, 0, len(offsets))
+	for _, id := range ids {
+		offset, ok := offsets[id]
+		if !ok {
+			continue
+		}
+		delete(offsets, id) // Return each passenger once, even if its ID is repeated.
+		record, err := readCSVRecordAt(seeker, offset)
+		if err != nil {
+			return nil, err
+		}
+		p, err := recordToPassenger(record)
+		if err != nil {
+			slog.WarnContext(ctx, "skipping invalid passenger record", "file", r.filePath, "passenger_id", id, "error", err)
+			continue
+		}
+		passengers = append(passengers, p)
+	}
+	return passengers, nil
+}
+
+// GetFares extracts all valid fare values from the CSV file.
+func (r *CSVRepository) GetFares(ctx context.Context) ([]float64, error) {
 	var fares []float64
-	for _, p := range passengers {
+	for p, err := range r.Passengers(ctx) {
+		if err != nil {
+			return nil, err
+		}
 		if p.Fare != nil {
 			fares = append(fares, *p.Fare)
 		}
@@ -90,6 +212,38 @@ func (r *CSVRepository) GetFares() ([]float64, error) {
 	return fares, nil
 }
 
+// Version hashes the CSV content, recomputing it only when the file changes on disk.
+func (r *CSVRepository) Version(ctx context.Context) (DatasetVersion, error) {
+	stamp, modTime, err := r.stamp()
+	if err != nil {
+		return DatasetVersion{}, err
+	}
+	return r.version.get(stamp, func() (DatasetVersion, error) {
+		var invalid int
+		hash, rows, err := hashPassengers(r.passengers(ctx, &invalid))
+		return DatasetVersion{Hash: hash, ModTime: modTime, Rows: rows, Invalid: invalid}, err
+	})
+}
+
+// Ping confirms that the CSV file parses and has at least one valid row. It
+// reuses the cached dataset version, so the file is only read when it changed.
+func (r *CSVRepository) Ping(ctx context.Context) error {
+	version, err := r.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version.Rows == 0 {
+		return ErrEmptyDataset
+	}
+	return nil
+}
+
+// Close implements PassengerRepository. The CSV file is only open while it is
+// being read, so there is nothing to release.
+func (r *CSVRepository) Close() error {
+	return nil
+}
+
 // recordToPassenger is a utility function to convert a single CSV record (a slice of strings)
 // into a model.Passenger struct, handling type conversions and potential empty values.
 func recordToPassenger(record []string) (model.Passenger, error) {
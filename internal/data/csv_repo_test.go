@@ -1,8 +1,10 @@
 package data
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -36,7 +38,7 @@ func TestCSVGetAllPassengers(t *testing.T) {
 	repo, err := NewCSVRepository(filePath)
 	assert.NoError(t, err)
 
-	passengers, err := repo.GetAllPassengers()
+	passengers, err := repo.GetAllPassengers(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, passengers, 1)
 	assert.Equal(t, "John Doe", passengers[0].Name)
@@ -49,7 +51,7 @@ func TestCSVGetPassengerByID(t *testing.T) {
 	repo, err := NewCSVRepository(filePath)
 	assert.NoError(t, err)
 
-	passenger, err := repo.GetPassengerByID(1)
+	passenger, err := repo.GetPassengerByID(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.NotNil(t, passenger)
 	assert.Equal(t, "John Doe", passenger.Name)
@@ -62,9 +64,117 @@ func TestCSVGetFares(t *testing.T) {
 	repo, err := NewCSVRepository(filePath)
 	assert.NoError(t, err)
 
-	fares, err := repo.GetFares()
+	fares, err := repo.GetFares(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, fares, 2)
 	assert.Equal(t, 100.0, fares[0])
 	assert.Equal(t, 200.0, fares[1])
 }
+
+func TestCSVPassengersStopsEarly(t *testing.T) {
+	filePath := createTempCSV(t, "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n2,0,3,Jane Doe,female,25,1,1,54321,200.0,,C\n")
+	defer os.Remove(filePath)
+
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+
+	var ids []int
+	for p, err := range repo.Passengers(context.Background()) {
+		assert.NoError(t, err)
+		ids = append(ids, p.PassengerID)
+		break
+	}
+	assert.Equal(t, []int{1}, ids)
+}
+
+func TestCSVPassengersCancelled(t *testing.T) {
+	filePath := createTempCSV(t, "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n")
+	defer os.Remove(filePath)
+
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range repo.Passengers(ctx) {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestCSVVersion(t *testing.T) {
+	filePath := createTempCSV(t, "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n")
+	defer os.Remove(filePath)
+
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+
+	v1, err := repo.Version(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, v1.Hash, 64)
+	assert.Equal(t, 1, v1.Rows)
+	assert.False(t, v1.ModTime.IsZero())
+
+	again, err := repo.Version(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, v1, again)
+
+	content := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n1,1,1,John Doe,male,31,0,0,12345,100.0,C123,S\n" +
+		"x,1,1,Bad Row,male,31,0,0,12345,100.0,C123,S\n"
+	assert.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	later := v1.ModTime.Add(time.Second)
+	assert.NoError(t, os.Chtimes(filePath, later, later))
+
+	v2, err := repo.Version(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, v1.Hash, v2.Hash)
+	assert.Equal(t, 1, v2.Rows)
+	assert.Equal(t, 1, v2.Invalid)
+	assert.False(t, v2.LoadedAt.Before(v1.LoadedAt))
+}
+
+func TestCSVGetPassengersByIDs(t *testing.T) {
+	filePath := createTempCSV(t, "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"+
+		"1,1,1,\"Doe, Mr. John\",male,30,0,0,12345,100.0,C123,S\n"+
+		"2,0,3,\"Doe, Mrs. Jane\",female,25,1,1,54321,200.0,,C\n"+
+		"3,0,3,Sam Roe,male,,0,0,999,7.25,,S\n")
+	defer os.Remove(filePath)
+
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+
+	passengers, err := repo.GetPassengersByIDs(context.Background(), []int{3, 42, 1, 3})
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+	assert.Equal(t, "Sam Roe", passengers[0].Name)
+	assert.Nil(t, passengers[0].Age)
+	assert.Equal(t, "Doe, Mr. John", passengers[1].Name)
+
+	// The index must follow changes to the file.
+	content := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n42,1,2,New Row,female,5,0,0,1,1.0,,Q\n"
+	assert.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(filePath, later, later))
+
+	passengers, err = repo.GetPassengersByIDs(context.Background(), []int{1, 42})
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 1)
+	assert.Equal(t, "New Row", passengers[0].Name)
+}
+
+func TestCSVPing(t *testing.T) {
+	header := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"
+	filePath := createTempCSV(t, header+"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n")
+	defer os.Remove(filePath)
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Ping(context.Background()))
+
+	emptyPath := createTempCSV(t, header)
+	defer os.Remove(emptyPath)
+	empty, err := NewCSVRepository(emptyPath)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, empty.Ping(context.Background()), ErrEmptyDataset)
+
+	os.Remove(filePath)
+	assert.Error(t, repo.Ping(context.Background()), "a deleted file fails the check")
+}
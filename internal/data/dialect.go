@@ -0,0 +1,75 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect holds the SQL that differs between the database engines behind the
+// SQL repositories. Shared queries are written with ? placeholders and
+// rebound to the engine's bind variable syntax.
+type dialect struct {
+	name string
+	// bindvar returns the placeholder of the n-th argument, counting from 1.
+	// Engines that take ? need none.
+	bindvar func(n int) string
+	// schema creates the passengers table unless it exists.
+	schema string
+}
+
+// rebind rewrites the ? placeholders in query for the dialect.
+func (d dialect) rebind(query string) string {
+	if d.bindvar == nil {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(d.bindvar(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var sqliteDialect = dialect{
+	name: "sqlite",
+	schema: `CREATE TABLE IF NOT EXISTS passengers (
+		PassengerId INTEGER PRIMARY KEY,
+		Survived INTEGER,
+		Pclass INTEGER,
+		Name TEXT,
+		Sex TEXT,
+		Age REAL,
+		SibSp INTEGER,
+		Parch INTEGER,
+		Ticket TEXT,
+		Fare REAL,
+		Cabin TEXT,
+		Embarked TEXT
+	)`,
+}
+
+// postgresDialect uses $n bind variables. Column names are left unquoted, so
+// PostgreSQL folds them to lower case in the schema and the queries alike.
+var postgresDialect = dialect{
+	name:    "postgres",
+	bindvar: func(n int) string { return "$" + strconv.Itoa(n) },
+	schema: `CREATE TABLE IF NOT EXISTS passengers (
+		PassengerId INTEGER PRIMARY KEY,
+		Survived INTEGER,
+		Pclass INTEGER,
+		Name TEXT,
+		Sex TEXT,
+		Age DOUBLE PRECISION,
+		SibSp INTEGER,
+		Parch INTEGER,
+		Ticket TEXT,
+		Fare DOUBLE PRECISION,
+		Cabin TEXT,
+		Embarked TEXT
+	)`,
+}
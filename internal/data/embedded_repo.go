@@ -0,0 +1,21 @@
+package data
+
+import (
+	dataset "github.com/dhope-nagesh/titanic-go-service/data"
+)
+
+func init() {
+	RegisterSource("embedded", func(struct{}) (PassengerRepository, error) {
+		repo, err := NewEmbeddedRepository()
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+}
+
+// NewEmbeddedRepository serves the Titanic dataset compiled into the binary,
+// parsed exactly as CSVRepository parses a file on disk. It has no settings.
+func NewEmbeddedRepository() (*CSVRepository, error) {
+	return NewCSVRepositoryFS(dataset.FS, dataset.CSVFile)
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	dataset "github.com/dhope-nagesh/titanic-go-service/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedRepository(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepository()
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Ping(ctx))
+
+	// The embedded copy is the file shipped in the data image.
+	onDisk, err := NewCSVRepository("../../data/" + dataset.CSVFile)
+	assert.NoError(t, err)
+	want, err := onDisk.Version(ctx)
+	assert.NoError(t, err)
+	got, err := repo.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, want.Hash, got.Hash)
+	assert.Equal(t, 891, got.Rows)
+	assert.True(t, got.ModTime.IsZero(), "embedded files have no modification time")
+
+	p, err := repo.GetPassengerByID(ctx, 891)
+	assert.NoError(t, err)
+	assert.Equal(t, "Dooley, Mr. Patrick", p.Name)
+	_, err = repo.GetPassengerByID(ctx, 892)
+	assert.ErrorIs(t, err, ErrPassengerNotFound)
+
+	assert.Len(t, dataset.Checksum(), 64)
+}
@@ -0,0 +1,530 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// FailoverConfig tunes a FailoverRepository.
+type FailoverConfig struct {
+	// FailureThreshold is the number of consecutive primary failures that opens
+	// the circuit, sending every call to the secondary.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a single call is let
+	// through to the primary to test whether it has recovered.
+	OpenTimeout time.Duration
+	// Shadow repeats the reads served by the primary on the secondary, in the
+	// background, and logs any difference between their results.
+	Shadow bool
+	// ShadowTimeout bounds each shadow read.
+	ShadowTimeout time.Duration
+}
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultShadowTimeout    = 5 * time.Second
+	// maxShadowReads bounds the shadow reads in flight. Reads beyond it are
+	// skipped rather than queued, so a slow secondary cannot pile them up.
+	maxShadowReads = 4
+)
+
+// Backend is a repository together with the data source name it was opened from.
+type Backend struct {
+	Name string
+	Repo PassengerRepository
+}
+
+// FailoverStats is a snapshot of the activity of a FailoverRepository.
+type FailoverStats struct {
+	// Served counts the calls answered by each backend, by backend name and then operation.
+	Served map[string]map[string]uint64
+	// Fallbacks counts the calls that failed on the primary and were retried on the secondary.
+	Fallbacks uint64
+	// CircuitOpen is whether calls currently bypass the primary.
+	CircuitOpen bool
+	// ShadowMismatches counts the shadow reads whose result differed, by operation.
+	ShadowMismatches map[string]uint64
+	// ShadowErrors counts the shadow reads that failed on the secondary.
+	ShadowErrors uint64
+	// PrimaryOnlyImports counts the imports applied to the primary, which leave
+	// the secondary without the imported rows.
+	PrimaryOnlyImports uint64
+}
+
+// FailoverRepository serves passengers from a primary backend and falls back
+// to a secondary one when the primary fails. A circuit breaker stops calling
+// the primary after repeated failures and probes it again after a while.
+// A passenger that does not exist, or a call cancelled by its caller, is not a
+// failure. Imports go to the primary only.
+type FailoverRepository struct {
+	primary   Backend
+	secondary Backend
+	cfg       FailoverConfig
+	breaker   breaker
+
+	// openMu guards primary.Repo, which openPrimary sets on a later probe when
+	// the primary could not be opened at first.
+	openMu      sync.Mutex
+	openPrimary func() (PassengerRepository, error)
+
+	shadowSlots chan struct{}
+	shadowWG    sync.WaitGroup
+
+	mu    sync.Mutex
+	stats FailoverStats
+}
+
+// NewFailoverRepository combines primary and secondary. A nil primary
+// repository, e.g. one that failed to open, leaves the secondary serving alone.
+func NewFailoverRepository(primary, secondary Backend, cfg FailoverConfig) *FailoverRepository {
+	return newFailoverRepository(primary, secondary, cfg, nil)
+}
+
+// newFailoverRepository is NewFailoverRepository with a way to open a nil
+// primary. The circuit then starts open, and each probe tries to open it.
+func newFailoverRepository(primary, secondary Backend, cfg FailoverConfig, openPrimary func() (PassengerRepository, error)) *FailoverRepository {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultOpenTimeout
+	}
+	if cfg.ShadowTimeout <= 0 {
+		cfg.ShadowTimeout = defaultShadowTimeout
+	}
+	r := &FailoverRepository{
+		primary:     primary,
+		secondary:   secondary,
+		cfg:         cfg,
+		breaker:     breaker{threshold: cfg.FailureThreshold, openTimeout: cfg.OpenTimeout, now: time.Now},
+		openPrimary: openPrimary,
+		shadowSlots: make(chan struct{}, maxShadowReads),
+		stats: FailoverStats{
+			Served:           make(map[string]map[string]uint64),
+			ShadowMismatches: make(map[string]uint64),
+		},
+	}
+	switch {
+	case primary.Repo != nil:
+	case openPrimary != nil:
+		r.breaker.failures, r.breaker.openUntil = cfg.FailureThreshold, r.breaker.now().Add(cfg.OpenTimeout)
+	default:
+		r.breaker.disabled = true
+	}
+	return r
+}
+
+// OpenFailover opens the data sources named primary and secondary, reading
+// their settings with decode, and combines them. If the primary cannot be
+// opened, the secondary serves alone and opening the primary is tried again
+// every OpenTimeout; the secondary must open.
+func OpenFailover(primary, secondary string, decode func(source string, v any) error, cfg FailoverConfig) (*FailoverRepository, error) {
+	open := func(name string) (PassengerRepository, error) {
+		return Open(name, func(v any) error { return decode(name, v) })
+	}
+	secondaryRepo, err := open(secondary)
+	if err != nil {
+		return nil, fmt.Errorf("could not open fallback data source %q: %w", secondary, err)
+	}
+	primaryRepo, err := open(primary)
+	if err != nil {
+		slog.Error("could not open data source, serving from the fallback", "source", primary, "fallback", secondary, "error", err)
+		primaryRepo = nil
+	}
+	return newFailoverRepository(Backend{Name: primary, Repo: primaryRepo}, Backend{Name: secondary, Repo: secondaryRepo}, cfg,
+		func() (PassengerRepository, error) { return open(primary) }), nil
+}
+
+// primaryRepo returns the primary repository, opening it first if it could
+// not be opened before. It is only called for calls the breaker lets through.
+func (r *FailoverRepository) primaryRepo() (PassengerRepository, error) {
+	r.openMu.Lock()
+	defer r.openMu.Unlock()
+	if r.primary.Repo != nil {
+		return r.primary.Repo, nil
+	}
+	if r.openPrimary == nil {
+		return nil, errPrimaryNotOpen
+	}
+	repo, err := r.openPrimary()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errPrimaryNotOpen, err)
+	}
+	slog.Info("opened data source", "source", r.primary.Name)
+	r.primary.Repo, r.openPrimary = repo, nil
+	return repo, nil
+}
+
+var errPrimaryNotOpen = errors.New("not open")
+
+// openedPrimary returns the primary repository, or nil if it is not open yet.
+func (r *FailoverRepository) openedPrimary() PassengerRepository {
+	r.openMu.Lock()
+	defer r.openMu.Unlock()
+	return r.primary.Repo
+}
+
+// Backends returns the primary and the secondary. The primary's Repo is nil
+// while it is not open.
+func (r *FailoverRepository) Backends() []Backend {
+	return []Backend{{Name: r.primary.Name, Repo: r.openedPrimary()}, r.secondary}
+}
+
+// Stats returns a snapshot of the calls served so far.
+func (r *FailoverRepository) Stats() FailoverStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Served = make(map[string]map[string]uint64, len(r.stats.Served))
+	for backend, ops := range r.stats.Served {
+		stats.Served[backend] = make(map[string]uint64, len(ops))
+		for op, n := range ops {
+			stats.Served[backend][op] = n
+		}
+	}
+	stats.ShadowMismatches = make(map[string]uint64, len(r.stats.ShadowMismatches))
+	for op, n := range r.stats.ShadowMismatches {
+		stats.ShadowMismatches[op] = n
+	}
+	stats.CircuitOpen = r.breaker.isOpen()
+	return stats
+}
+
+func (r *FailoverRepository) served(backend, op string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats.Served[backend] == nil {
+		r.stats.Served[backend] = make(map[string]uint64)
+	}
+	r.stats.Served[backend][op]++
+}
+
+// primaryFailed records the outcome of a call to the primary with the breaker
+// and reports whether it should be retried on the secondary.
+func (r *FailoverRepository) primaryFailed(ctx context.Context, op string, err error) bool {
+	switch {
+	case err == nil || errors.Is(err, ErrPassengerNotFound):
+		if r.breaker.success() {
+			slog.InfoContext(ctx, "data source recovered, leaving the fallback", "source", r.primary.Name)
+		}
+		return false
+	case ctx.Err() != nil:
+		r.breaker.release()
+		return false
+	}
+	if r.breaker.failure() {
+		slog.ErrorContext(ctx, "data source keeps failing, serving from the fallback", "source", r.primary.Name,
+			"fallback", r.secondary.Name, "retry_in", r.cfg.OpenTimeout.String(), "error", err)
+	} else {
+		slog.WarnContext(ctx, "data source call failed, retrying on the fallback", "source", r.primary.Name,
+			"fallback", r.secondary.Name, "operation", op, "error", err)
+	}
+	r.mu.Lock()
+	r.stats.Fallbacks++
+	r.mu.Unlock()
+	return true
+}
+
+// call runs fn on the primary, unless the circuit is open, and on the
+// secondary when the primary fails. compare, if not nil, is used for shadow reads.
+func call[T any](ctx context.Context, r *FailoverRepository, op string, fn func(context.Context, PassengerRepository) (T, error), compare func(a, b T) string) (T, error) {
+	if r.breaker.allow() {
+		var v T
+		repo, err := r.primaryRepo()
+		if err == nil {
+			v, err = fn(ctx, repo)
+		}
+		if !r.primaryFailed(ctx, op, err) {
+			r.served(r.primary.Name, op)
+			if compare != nil && r.cfg.Shadow && ctx.Err() == nil {
+				shadowRead(ctx, r, op, v, err, fn, compare)
+			}
+			return v, err
+		}
+	}
+	v, err := fn(ctx, r.secondary.Repo)
+	r.served(r.secondary.Name, op)
+	return v, err
+}
+
+// shadowRead repeats a read on the secondary in the background and logs how
+// its result differs from want, the result of the primary.
+func shadowRead[T any](ctx context.Context, r *FailoverRepository, op string, want T, wantErr error, fn func(context.Context, PassengerRepository) (T, error), compare func(a, b T) string) {
+	select {
+	case r.shadowSlots <- struct{}{}:
+	default:
+		return
+	}
+	r.shadowWG.Add(1)
+	go func() {
+		defer r.shadowWG.Done()
+		defer func() { <-r.shadowSlots }()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.cfg.ShadowTimeout)
+		defer cancel()
+
+		got, err := fn(ctx, r.secondary.Repo)
+		var diff string
+		switch {
+		case err != nil && !errors.Is(err, ErrPassengerNotFound):
+			slog.WarnContext(ctx, "shadow read failed", "operation", op, "source", r.secondary.Name, "error", err)
+			r.mu.Lock()
+			r.stats.ShadowErrors++
+			r.mu.Unlock()
+			return
+		case (err == nil) != (wantErr == nil):
+			diff = fmt.Sprintf("%s returned %v, %s returned %v", r.primary.Name, errOrOK(wantErr), r.secondary.Name, errOrOK(err))
+		case err == nil:
+			diff = compare(want, got)
+		}
+		if diff == "" {
+			return
+		}
+		slog.WarnContext(ctx, "shadow read differs", "operation", op, "source", r.primary.Name, "shadow", r.secondary.Name, "difference", diff)
+		r.mu.Lock()
+		r.stats.ShadowMismatches[op]++
+		r.mu.Unlock()
+	}()
+}
+
+func errOrOK(err error) any {
+	if err == nil {
+		return "a result"
+	}
+	return err
+}
+
+// diffPassengers describes the first difference between two passenger lists,
+// or returns "" if they hold the same passengers. Backends may return
+// passengers in different orders, so they are matched by PassengerId.
+func diffPassengers(a, b []model.Passenger) string {
+	if len(a) != len(b) {
+		return fmt.Sprintf("%d passengers instead of %d", len(b), len(a))
+	}
+	byID := make(map[int]model.Passenger, len(b))
+	for _, p := range b {
+		byID[p.PassengerID] = p
+	}
+	for _, p := range a {
+		other, ok := byID[p.PassengerID]
+		if !ok {
+			return fmt.Sprintf("passenger %d is missing", p.PassengerID)
+		}
+		if !reflect.DeepEqual(p, other) {
+			return fmt.Sprintf("passenger %d differs", p.PassengerID)
+		}
+	}
+	return ""
+}
+
+// diffFares describes how two fare lists differ, or returns "" if they hold
+// the same fares. The order of fares is unspecified, so they are compared sorted.
+func diffFares(a, b []float64) string {
+	if slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b))) {
+		return ""
+	}
+	return fmt.Sprintf("%d fares differ from the %d expected", len(b), len(a))
+}
+
+func (r *FailoverRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	return call(ctx, r, "GetAllPassengers", func(ctx context.Context, repo PassengerRepository) ([]model.Passenger, error) {
+		return repo.GetAllPassengers(ctx)
+	}, diffPassengers)
+}
+
+func (r *FailoverRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	return call(ctx, r, "GetPassengerByID", func(ctx context.Context, repo PassengerRepository) (*model.Passenger, error) {
+		return repo.GetPassengerByID(ctx, id)
+	}, func(a, b *model.Passenger) string {
+		return diffPassengers([]model.Passenger{*a}, []model.Passenger{*b})
+	})
+}
+
+func (r *FailoverRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	return call(ctx, r, "GetPassengersByIDs", func(ctx context.Context, repo PassengerRepository) ([]model.Passenger, error) {
+		return repo.GetPassengersByIDs(ctx, ids)
+	}, diffPassengers)
+}
+
+func (r *FailoverRepository) GetFares(ctx context.Context) ([]float64, error) {
+	return call(ctx, r, "GetFares", func(ctx context.Context, repo PassengerRepository) ([]float64, error) {
+		return repo.GetFares(ctx)
+	}, diffFares)
+}
+
+// Version is not shadowed: the modification times of two backends differ even
+// when they hold the same passengers.
+func (r *FailoverRepository) Version(ctx context.Context) (DatasetVersion, error) {
+	return call(ctx, r, "Version", func(ctx context.Context, repo PassengerRepository) (DatasetVersion, error) {
+		return repo.Version(ctx)
+	}, nil)
+}
+
+// Passengers falls back to the secondary only if the primary fails before
+// yielding its first passenger. A failure after that ends the stream, since the
+// caller has already consumed part of it.
+func (r *FailoverRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	const op = "Passengers"
+	return func(yield func(model.Passenger, error) bool) {
+		if r.breaker.allow() {
+			var failure error
+			started := false
+			passengers := func(yield func(model.Passenger, error) bool) {
+				repo, err := r.primaryRepo()
+				if err != nil {
+					yield(model.Passenger{}, err)
+					return
+				}
+				repo.Passengers(ctx)(yield)
+			}
+			for p, err := range passengers {
+				if err != nil && !started {
+					failure = err
+					break
+				}
+				started = true
+				if !yield(p, err) || err != nil {
+					r.primaryFailed(ctx, op, err)
+					r.served(r.primary.Name, op)
+					return
+				}
+			}
+			if !r.primaryFailed(ctx, op, failure) {
+				r.served(r.primary.Name, op)
+				if failure != nil {
+					yield(model.Passenger{}, failure)
+				}
+				return
+			}
+		}
+		r.served(r.secondary.Name, op)
+		for p, err := range r.secondary.Repo.Passengers(ctx) {
+			if !yield(p, err) {
+				return
+			}
+		}
+	}
+}
+
+// Ping succeeds while either backend can serve. A primary that is not open
+// yet is reported as such until a probe of the circuit breaker opens it.
+func (r *FailoverRepository) Ping(ctx context.Context) error {
+	var primaryErr error
+	if primary := r.openedPrimary(); primary == nil {
+		primaryErr = errPrimaryNotOpen
+	} else if primaryErr = primary.Ping(ctx); primaryErr == nil {
+		return nil
+	}
+	if err := r.secondary.Repo.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w; fallback %s: %w", r.primary.Name, primaryErr, r.secondary.Name, err)
+	}
+	return nil
+}
+
+// Import loads records into the primary, if it supports imports. The
+// secondary is left as it is, so it serves without the imported rows whenever
+// the primary fails; each such import is logged and counted in the stats.
+func (r *FailoverRepository) Import(ctx context.Context, mode ImportMode, records []ImportRecord) (model.ImportSummary, error) {
+	importer, ok := r.openedPrimary().(Importer)
+	if !ok {
+		return model.ImportSummary{}, ErrImportNotSupported
+	}
+	summary, err := importer.Import(ctx, mode, records)
+	if err == nil {
+		slog.WarnContext(ctx, "import applied to the primary data source only, the fallback does not have it",
+			"source", r.primary.Name, "fallback", r.secondary.Name, "inserted", summary.Inserted, "updated", summary.Updated)
+		r.mu.Lock()
+		r.stats.PrimaryOnlyImports++
+		r.mu.Unlock()
+	}
+	return summary, err
+}
+
+// Close waits for shadow reads in flight and closes both backends.
+func (r *FailoverRepository) Close() error {
+	r.shadowWG.Wait()
+	var errs []error
+	if primary := r.openedPrimary(); primary != nil {
+		errs = append(errs, primary.Close())
+	}
+	errs = append(errs, r.secondary.Repo.Close())
+	return errors.Join(errs...)
+}
+
+// breaker is a circuit breaker guarding the primary. After threshold
+// consecutive failures it opens for openTimeout, then lets a single probe
+// call through: a success closes it, a failure opens it again.
+type breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+	// disabled keeps the circuit open for good, when there is no primary.
+	disabled bool
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time // Zero while the circuit is closed.
+	probing   bool
+}
+
+// allow reports whether a call may go to the primary. Every allowed call must
+// be followed by success, failure or release.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.disabled:
+		return false
+	case b.openUntil.IsZero():
+		return true
+	case b.probing || b.now().Before(b.openUntil):
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success records a successful call and reports whether it closed the circuit.
+func (b *breaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	closed := !b.openUntil.IsZero()
+	b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+	return closed
+}
+
+// failure records a failed call and reports whether it opened the circuit.
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	wasOpen := !b.openUntil.IsZero()
+	if wasOpen || b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.openTimeout)
+	}
+	b.probing = false
+	return !wasOpen && !b.openUntil.IsZero()
+}
+
+// release ends a call whose outcome says nothing about the primary, such as
+// one cancelled by its caller.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.disabled || !b.openUntil.IsZero()
+}
@@ -0,0 +1,346 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyRepository serves a CSV file and fails every call while err is set.
+type flakyRepository struct {
+	*CSVRepository
+
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (r *flakyRepository) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+func (r *flakyRepository) check() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return r.err
+}
+
+func (r *flakyRepository) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func (r *flakyRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	return r.CSVRepository.GetPassengerByID(ctx, id)
+}
+
+func (r *flakyRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	return r.CSVRepository.GetAllPassengers(ctx)
+}
+
+func (r *flakyRepository) Ping(ctx context.Context) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	return r.CSVRepository.Ping(ctx)
+}
+
+func newFlakyRepository(t *testing.T, content string) *flakyRepository {
+	filePath := filepath.Join(t.TempDir(), "passengers.csv")
+	assert.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+	return &flakyRepository{CSVRepository: repo}
+}
+
+func newTestFailover(t *testing.T, cfg FailoverConfig) (*FailoverRepository, *flakyRepository, *flakyRepository) {
+	primary := newFlakyRepository(t, httpTestHeader+"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n")
+	secondary := newFlakyRepository(t, httpTestHeader+"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n"+
+		"2,0,3,Jane Roe,female,25,1,1,54321,200.0,,C\n")
+	return NewFailoverRepository(Backend{Name: "sqlite", Repo: primary}, Backend{Name: "csv", Repo: secondary}, cfg), primary, secondary
+}
+
+func TestFailoverRepository(t *testing.T) {
+	repo, primary, _ := newTestFailover(t, FailoverConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+	ctx := context.Background()
+
+	p, err := repo.GetPassengerByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", p.Name)
+	_, err = repo.GetPassengerByID(ctx, 2)
+	assert.ErrorIs(t, err, ErrPassengerNotFound, "a missing passenger is an answer, not a failure")
+
+	primary.fail(errors.New("no such table: passengers"))
+	p, err = repo.GetPassengerByID(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Roe", p.Name)
+
+	stats := repo.Stats()
+	assert.Equal(t, uint64(2), stats.Served["sqlite"]["GetPassengerByID"])
+	assert.Equal(t, uint64(1), stats.Served["csv"]["GetPassengerByID"])
+	assert.Equal(t, uint64(1), stats.Fallbacks)
+	assert.False(t, stats.CircuitOpen)
+
+	// A call cancelled by its caller is not blamed on the primary.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	primary.fail(context.Canceled)
+	_, err = repo.GetPassengerByID(cancelled, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, uint64(1), repo.Stats().Fallbacks)
+	assert.NoError(t, repo.Ping(ctx))
+}
+
+func TestFailoverCircuitBreaker(t *testing.T) {
+	repo, primary, _ := newTestFailover(t, FailoverConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+	now := time.Now()
+	repo.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	primary.fail(errors.New("disk I/O error"))
+	for range 2 {
+		_, err := repo.GetAllPassengers(ctx)
+		assert.NoError(t, err)
+	}
+	assert.True(t, repo.Stats().CircuitOpen)
+
+	// While the circuit is open the primary is not called at all.
+	calls := primary.callCount()
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+	assert.Equal(t, calls, primary.callCount())
+
+	// After the timeout one call probes the primary; a failure opens the circuit again.
+	now = now.Add(time.Minute)
+	_, err = repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, calls+1, primary.callCount())
+	_, err = repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, calls+1, primary.callCount())
+
+	now = now.Add(time.Minute)
+	primary.fail(nil)
+	passengers, err = repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 1, "the recovered primary serves again")
+	assert.False(t, repo.Stats().CircuitOpen)
+}
+
+func TestFailoverReopensPrimary(t *testing.T) {
+	primary := newFlakyRepository(t, httpTestHeader+"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n")
+	secondary := newFlakyRepository(t, httpTestHeader+"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n"+
+		"2,0,3,Jane Roe,female,25,1,1,54321,200.0,,C\n")
+	opens := 0
+	open := func() (PassengerRepository, error) {
+		opens++
+		if opens == 1 {
+			return nil, errors.New("database is locked")
+		}
+		return primary, nil
+	}
+	repo := newFailoverRepository(Backend{Name: "sqlite"}, Backend{Name: "csv", Repo: secondary},
+		FailoverConfig{FailureThreshold: 2, OpenTimeout: time.Minute}, open)
+	now := time.Now()
+	repo.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+	assert.Zero(t, opens, "the circuit starts open")
+	assert.True(t, repo.Stats().CircuitOpen)
+	secondary.fail(errors.New("disk I/O error"))
+	assert.ErrorContains(t, repo.Ping(ctx), "sqlite: not open")
+	secondary.fail(nil)
+
+	// Each probe tries to open the primary; a failure keeps the circuit open.
+	now = now.Add(time.Minute)
+	passengers, err = repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+	assert.Equal(t, 1, opens)
+	assert.True(t, repo.Stats().CircuitOpen)
+
+	now = now.Add(time.Minute)
+	passengers, err = collect(repo.Passengers(ctx))
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 1, "the primary serves once it opens")
+	assert.Equal(t, 2, opens)
+	assert.False(t, repo.Stats().CircuitOpen)
+
+	secondary.fail(errors.New("disk I/O error"))
+	assert.NoError(t, repo.Ping(ctx), "the opened primary answers pings")
+	_, err = repo.GetPassengerByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, opens)
+}
+
+func TestFailoverImport(t *testing.T) {
+	primary := newTempSQLiteRepository(t)
+	secondary := newFlakyRepository(t, httpTestHeader+"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n")
+	repo := NewFailoverRepository(Backend{Name: "sqlite", Repo: primary}, Backend{Name: "csv", Repo: secondary}, FailoverConfig{})
+
+	summary, err := repo.Import(context.Background(), ImportUpsert, parseRecords(t, "3,1,2,New Three,male,,0,0,X,10,,Q\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Inserted)
+	assert.Equal(t, uint64(1), repo.Stats().PrimaryOnlyImports)
+}
+
+func TestFailoverPassengers(t *testing.T) {
+	repo, primary, _ := newTestFailover(t, FailoverConfig{})
+	ctx := context.Background()
+
+	passengers, err := collect(repo.Passengers(ctx))
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 1)
+
+	// A primary that cannot be read from the start is replaced by the secondary.
+	os.Remove(primary.filePath)
+	passengers, err = collect(repo.Passengers(ctx))
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+
+	stats := repo.Stats()
+	assert.Equal(t, uint64(1), stats.Served["sqlite"]["Passengers"])
+	assert.Equal(t, uint64(1), stats.Served["csv"]["Passengers"])
+	assert.Equal(t, uint64(1), stats.Fallbacks)
+}
+
+func TestFailoverShadowReads(t *testing.T) {
+	repo, _, _ := newTestFailover(t, FailoverConfig{Shadow: true})
+	ctx := context.Background()
+
+	_, err := repo.GetPassengerByID(ctx, 1)
+	assert.NoError(t, err)
+	_, err = repo.GetPassengerByID(ctx, 2)
+	assert.ErrorIs(t, err, ErrPassengerNotFound)
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 1, "the primary's result is returned")
+
+	assert.NoError(t, repo.Close()) // Waits for the shadow reads.
+	stats := repo.Stats()
+	assert.Equal(t, map[string]uint64{"GetPassengerByID": 1, "GetAllPassengers": 1}, stats.ShadowMismatches)
+	assert.Zero(t, stats.Served["csv"]["GetPassengerByID"], "shadow reads are not counted as served")
+}
+
+func TestDiffPassengers(t *testing.T) {
+	a := []model.Passenger{{PassengerID: 1, Name: "A"}, {PassengerID: 2, Name: "B"}}
+	assert.Empty(t, diffPassengers(a, a))
+	assert.Equal(t, "1 passengers instead of 2", diffPassengers(a, a[:1]))
+	b := []model.Passenger{{PassengerID: 1, Name: "A"}, {PassengerID: 2, Name: "C"}}
+	assert.Equal(t, "passenger 2 differs", diffPassengers(a, b))
+	assert.Empty(t, diffPassengers(a, []model.Passenger{a[1], a[0]}), "order does not matter")
+	assert.Equal(t, "passenger 2 is missing", diffPassengers(a, []model.Passenger{a[0], {PassengerID: 3}}))
+}
+
+func TestDiffFares(t *testing.T) {
+	assert.Empty(t, diffFares([]float64{7.25, 100}, []float64{100, 7.25}))
+	assert.Equal(t, "2 fares differ from the 2 expected", diffFares([]float64{7.25, 100}, []float64{7.25, 200}))
+}
+
+func TestFailoverShadowReadsAcrossBackends(t *testing.T) {
+	ctx := context.Background()
+	passengers := fileTestPassengers(t)
+	backends := map[string]func(t *testing.T) PassengerRepository{
+		"csv": func(t *testing.T) PassengerRepository {
+			repo, err := NewCSVRepository(createTempCSV(t, fileTestCSV))
+			assert.NoError(t, err)
+			return repo
+		},
+		"sqlite": func(t *testing.T) PassengerRepository {
+			repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "titanic.db"))
+			assert.NoError(t, err)
+			assert.NoError(t, repo.CreateSchema(ctx))
+			_, err = repo.Import(ctx, ImportReplace, parseRecords(t, strings.TrimPrefix(fileTestCSV, importHeader)))
+			assert.NoError(t, err)
+			return repo
+		},
+		"parquet": func(t *testing.T) PassengerRepository {
+			filePath := filepath.Join(t.TempDir(), "passengers.parquet")
+			writeParquet(t, filePath, passengers)
+			repo, err := NewParquetRepository(filePath)
+			assert.NoError(t, err)
+			return repo
+		},
+		"ndjson": func(t *testing.T) PassengerRepository {
+			filePath := filepath.Join(t.TempDir(), "passengers.jsonl")
+			writeNDJSON(t, filePath, passengers)
+			repo, err := NewNDJSONRepository(filePath)
+			assert.NoError(t, err)
+			return repo
+		},
+	}
+
+	for primary, openPrimary := range backends {
+		for secondary, openSecondary := range backends {
+			if primary == secondary {
+				continue
+			}
+			t.Run(primary+"/"+secondary, func(t *testing.T) {
+				repo := NewFailoverRepository(Backend{Name: primary, Repo: openPrimary(t)},
+					Backend{Name: secondary, Repo: openSecondary(t)}, FailoverConfig{Shadow: true})
+				_, err := repo.GetPassengersByIDs(ctx, []int{3, 1})
+				assert.NoError(t, err)
+				_, err = repo.GetAllPassengers(ctx)
+				assert.NoError(t, err)
+				_, err = repo.GetFares(ctx)
+				assert.NoError(t, err)
+
+				assert.NoError(t, repo.Close()) // Waits for the shadow reads.
+				assert.Empty(t, repo.Stats().ShadowMismatches)
+			})
+		}
+	}
+}
+
+func TestOpenFailover(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "titanic.csv")
+	assert.NoError(t, os.WriteFile(csvPath, []byte(fileTestCSV), 0o644))
+	decode := func(source string, v any) error {
+		switch cfg := v.(type) {
+		case *CSVConfig:
+			cfg.File = csvPath
+		case *SQLiteConfig:
+			cfg.File = filepath.Join(t.TempDir(), "missing", "titanic.db")
+		}
+		return nil
+	}
+
+	repo, err := OpenFailover("sqlite", "csv", decode, FailoverConfig{})
+	assert.NoError(t, err, "a primary that cannot be opened leaves the fallback serving")
+	defer repo.Close()
+	passengers, err := repo.GetAllPassengers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 3)
+	assert.True(t, repo.Stats().CircuitOpen)
+	backends := repo.Backends()
+	assert.Equal(t, []string{"sqlite", "csv"}, []string{backends[0].Name, backends[1].Name})
+	assert.Nil(t, backends[0].Repo, "the primary is not open")
+	assert.NoError(t, repo.Ping(context.Background()))
+	_, err = repo.Import(context.Background(), ImportAppendOnly, nil)
+	assert.ErrorIs(t, err, ErrImportNotSupported)
+
+	_, err = OpenFailover("csv", "sqlite", decode, FailoverConfig{})
+	assert.ErrorContains(t, err, `could not open fallback data source "sqlite"`)
+}
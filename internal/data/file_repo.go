@@ -0,0 +1,318 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/filestamp"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/parquet-go/parquet-go"
+)
+
+func init() {
+	RegisterSource("parquet", func(cfg FileConfig) (PassengerRepository, error) {
+		return openFile(cfg.File, NewParquetRepository)
+	})
+	RegisterSource("ndjson", func(cfg FileConfig) (PassengerRepository, error) {
+		return openFile(cfg.File, NewNDJSONRepository)
+	})
+	RegisterSource("file", func(cfg FileConfig) (PassengerRepository, error) {
+		return OpenFile(cfg.File)
+	})
+}
+
+// FileConfig is the section of the configuration of a file data source:
+// data.parquet, data.ndjson or data.file.
+type FileConfig struct {
+	File string `mapstructure:"file"`
+}
+
+// fileFormats maps the extensions recognised by OpenFile to the repository reading them.
+var fileFormats = map[string]func(string) (PassengerRepository, error){
+	".csv":     func(path string) (PassengerRepository, error) { return openFile(path, NewCSVRepository) },
+	".parquet": func(path string) (PassengerRepository, error) { return openFile(path, NewParquetRepository) },
+	".ndjson":  func(path string) (PassengerRepository, error) { return openFile(path, NewNDJSONRepository) },
+	".jsonl":   func(path string) (PassengerRepository, error) { return openFile(path, NewNDJSONRepository) },
+}
+
+// OpenFile opens a passenger file in the format given by its extension: .csv,
+// .parquet, or .ndjson and .jsonl for newline-delimited JSON.
+func OpenFile(path string) (PassengerRepository, error) {
+	open, ok := fileFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("cannot tell the format of %s from its extension, valid extensions are: .csv, .jsonl, .ndjson, .parquet", path)
+	}
+	return open(path)
+}
+
+// openFile calls open and returns its repository as a PassengerRepository, so
+// that a failure yields a nil interface rather than a typed nil pointer.
+func openFile[R PassengerRepository](path string, open func(string) (R, error)) (PassengerRepository, error) {
+	repo, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// decodeFunc streams the passengers stored in file. Records that cannot be
+// read as a passenger are logged and skipped, and counted in *invalid when it
+// is not nil.
+type decodeFunc func(ctx context.Context, file *os.File, invalid *int) iter.Seq2[model.Passenger, error]
+
+// FileRepository serves passengers from a Parquet or newline-delimited JSON
+// file. The file is streamed on every call rather than held in memory.
+type FileRepository struct {
+	filePath string
+	decode   decodeFunc
+	version  versionCache
+}
+
+func newFileRepository(filePath string, decode decodeFunc) (*FileRepository, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, fmt.Errorf("cannot open data file: %w", err)
+	}
+	return &FileRepository{filePath: filePath, decode: decode}, nil
+}
+
+// NewParquetRepository serves a Parquet file with the columns of
+// model.ParquetPassenger, as written by the Parquet export.
+func NewParquetRepository(filePath string) (*FileRepository, error) {
+	return newFileRepository(filePath, decodeParquet)
+}
+
+// NewNDJSONRepository serves a file holding one JSON passenger per line, with
+// the field names of the API, as written by GET /passengers?format=ndjson.
+func NewNDJSONRepository(filePath string) (*FileRepository, error) {
+	return newFileRepository(filePath, decodeNDJSON)
+}
+
+// Passengers streams the passengers in the file, skipping invalid records.
+func (r *FileRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return r.passengers(ctx, nil)
+}
+
+func (r *FileRepository) passengers(ctx context.Context, invalid *int) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		file, err := os.Open(r.filePath)
+		if err != nil {
+			yield(model.Passenger{}, err)
+			return
+		}
+		defer file.Close()
+		for p, err := range r.decode(ctx, file, invalid) {
+			if !yield(p, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (r *FileRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	return collect(r.Passengers(ctx))
+}
+
+func (r *FileRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	passengers, err := r.GetPassengersByIDs(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(passengers) == 0 {
+		return nil, ErrPassengerNotFound
+	}
+	return &passengers[0], nil
+}
+
+// GetPassengersByIDs scans the file once for all requested IDs and returns the
+// passengers in the order their IDs were requested.
+func (r *FileRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	wanted := make(map[int]*model.Passenger, len(ids))
+	for _, id := range ids {
+		wanted[id] = nil
+	}
+	for p, err := range r.Passengers(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		if found, ok := wanted[p.PassengerID]; ok && found == nil {
+			wanted[p.PassengerID] = &p
+		}
+	}
+
+	passengers := make([]model.Passenger, 0, len(ids))
+	for _, id := range ids {
+		if p := wanted[id]; p != nil {
+			passengers = append(passengers, *p)
+			wanted[id] = nil // Return each passenger once, even if its ID is repeated.
+		}
+	}
+	return passengers, nil
+}
+
+func (r *FileRepository) GetFares(ctx context.Context) ([]float64, error) {
+	var fares []float64
+	for p, err := range r.Passengers(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		if p.Fare != nil {
+			fares = append(fares, *p.Fare)
+		}
+	}
+	return fares, nil
+}
+
+// Version hashes the passengers in the file, recomputing it only when the file changes on disk.
+func (r *FileRepository) Version(ctx context.Context) (DatasetVersion, error) {
+	stamp, modTime, err := filestamp.Of(r.filePath)
+	if err != nil {
+		return DatasetVersion{}, err
+	}
+	return r.version.get(stamp, func() (DatasetVersion, error) {
+		var invalid int
+		hash, rows, err := hashPassengers(r.passengers(ctx, &invalid))
+		return DatasetVersion{Hash: hash, ModTime: modTime, Rows: rows, Invalid: invalid}, err
+	})
+}
+
+// Ping confirms that the file can be read and has at least one valid record.
+func (r *FileRepository) Ping(ctx context.Context) error {
+	version, err := r.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version.Rows == 0 {
+		return ErrEmptyDataset
+	}
+	return nil
+}
+
+// Close implements PassengerRepository. The file is only open while it is
+// being read, so there is nothing to release.
+func (r *FileRepository) Close() error {
+	return nil
+}
+
+// normalizePassenger treats empty optional strings as missing, as
+// recordToPassenger does for empty CSV fields.
+func normalizePassenger(p *model.Passenger) {
+	if p.Cabin != nil && *p.Cabin == "" {
+		p.Cabin = nil
+	}
+	if p.Embarked != nil && *p.Embarked == "" {
+		p.Embarked = nil
+	}
+}
+
+// parquetBatchSize is the number of rows decoded from a Parquet file at a time.
+const parquetBatchSize = 256
+
+func decodeParquet(ctx context.Context, file *os.File, invalid *int) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		info, err := file.Stat()
+		if err != nil {
+			yield(model.Passenger{}, err)
+			return
+		}
+		pf, err := parquet.OpenFile(file, info.Size())
+		if err != nil {
+			yield(model.Passenger{}, fmt.Errorf("invalid Parquet file: %w", err))
+			return
+		}
+		reader := parquet.NewGenericReader[model.ParquetPassenger](pf)
+		defer reader.Close()
+
+		line := 0
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(model.Passenger{}, err)
+				return
+			}
+			// The optional fields of a row point into the batch, so each batch
+			// needs its own storage for the passengers to outlive the next Read.
+			rows := make([]model.ParquetPassenger, parquetBatchSize)
+			n, err := reader.Read(rows)
+			for _, row := range rows[:n] {
+				line++
+				if row.PassengerID <= 0 {
+					// A missing column, or a null in an optional one, reads as zero.
+					slog.WarnContext(ctx, "skipping invalid passenger record", "file", file.Name(), "row", line,
+						"error", fmt.Sprintf("invalid PassengerId: %d", row.PassengerID))
+					if invalid != nil {
+						*invalid++
+					}
+					continue
+				}
+				p := row.Passenger()
+				normalizePassenger(&p)
+				if !yield(p, nil) {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(model.Passenger{}, err)
+				return
+			}
+		}
+	}
+}
+
+// ndjsonPassenger decodes a JSON passenger. PassengerID is a pointer so a
+// missing ID can be told apart from zero.
+type ndjsonPassenger struct {
+	PassengerID *int `json:"passengerId"`
+	model.Passenger
+}
+
+func decodeNDJSON(ctx context.Context, file *os.File, invalid *int) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if err := ctx.Err(); err != nil {
+				yield(model.Passenger{}, err)
+				return
+			}
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+
+			var rec ndjsonPassenger
+			err := json.Unmarshal([]byte(text), &rec)
+			if err == nil && rec.PassengerID == nil {
+				err = errors.New("missing passengerId")
+			}
+			if err != nil {
+				slog.WarnContext(ctx, "skipping invalid passenger record", "file", file.Name(), "line", line, "error", err)
+				if invalid != nil {
+					*invalid++
+				}
+				continue
+			}
+			p := rec.Passenger
+			p.PassengerID = *rec.PassengerID
+			normalizePassenger(&p)
+			if !yield(p, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(model.Passenger{}, err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const fileTestCSV = "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n" +
+	"1,1,1,\"Doe, Mr. John\",male,30,0,0,12345,100.0,C123,S\n" +
+	"2,0,3,\"Doe, Mrs. Jane\",female,25,1,1,54321,200.0,,C\n" +
+	"3,0,3,Sam Roe,male,,0,0,999,7.25,,\n"
+
+// fileTestPassengers returns the passengers of fileTestCSV as the csv source reads them.
+func fileTestPassengers(t *testing.T) []model.Passenger {
+	filePath := createTempCSV(t, fileTestCSV)
+	defer os.Remove(filePath)
+	repo, err := NewCSVRepository(filePath)
+	assert.NoError(t, err)
+	passengers, err := repo.GetAllPassengers(context.Background())
+	assert.NoError(t, err)
+	return passengers
+}
+
+func writeParquet(t *testing.T, path string, passengers []model.Passenger) {
+	rows := make([]model.ParquetPassenger, len(passengers))
+	for i, p := range passengers {
+		rows[i] = model.NewParquetPassenger(p)
+	}
+	assert.NoError(t, parquet.WriteFile(path, rows))
+}
+
+func writeNDJSON(t *testing.T, path string, passengers []model.Passenger, extra ...string) {
+	var content []byte
+	for _, p := range passengers {
+		line, err := json.Marshal(p)
+		assert.NoError(t, err)
+		content = append(append(content, line...), '\n')
+	}
+	for _, line := range extra {
+		content = append(content, line+"\n"...)
+	}
+	assert.NoError(t, os.WriteFile(path, content, 0o644))
+}
+
+func TestParquetRepository(t *testing.T) {
+	want := fileTestPassengers(t)
+	filePath := filepath.Join(t.TempDir(), "passengers.parquet")
+	writeParquet(t, filePath, want)
+
+	repo, err := NewParquetRepository(filePath)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, want, passengers)
+	assert.Nil(t, passengers[2].Age)
+	assert.Nil(t, passengers[2].Embarked)
+
+	passenger, err := repo.GetPassengerByID(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "Doe, Mrs. Jane", passenger.Name)
+	_, err = repo.GetPassengerByID(ctx, 42)
+	assert.ErrorIs(t, err, ErrPassengerNotFound)
+
+	byIDs, err := repo.GetPassengersByIDs(ctx, []int{3, 42, 1, 3})
+	assert.NoError(t, err)
+	assert.Len(t, byIDs, 2)
+	assert.Equal(t, 3, byIDs[0].PassengerID)
+	assert.Equal(t, 1, byIDs[1].PassengerID)
+
+	fares, err := repo.GetFares(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{100, 200, 7.25}, fares)
+	assert.NoError(t, repo.Ping(ctx))
+}
+
+func TestParquetRepositoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	embedded, err := NewEmbeddedRepository()
+	assert.NoError(t, err)
+	want, err := embedded.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Greater(t, len(want), parquetBatchSize)
+
+	filePath := filepath.Join(t.TempDir(), "passengers.parquet")
+	writeParquet(t, filePath, want)
+	repo, err := NewParquetRepository(filePath)
+	assert.NoError(t, err)
+
+	// Every field must survive later batches being decoded.
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, want, passengers)
+
+	ids := make([]int, len(want))
+	for i, p := range want {
+		ids[i] = p.PassengerID
+	}
+	byIDs, err := repo.GetPassengersByIDs(ctx, ids)
+	assert.NoError(t, err)
+	assert.Equal(t, want, byIDs)
+}
+
+func TestParquetRepositoryInvalidFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "passengers.parquet")
+	assert.NoError(t, os.WriteFile(filePath, []byte("not parquet"), 0o644))
+
+	repo, err := NewParquetRepository(filePath)
+	assert.NoError(t, err)
+	_, err = repo.GetAllPassengers(context.Background())
+	assert.ErrorContains(t, err, "invalid Parquet file")
+	assert.Error(t, repo.Ping(context.Background()))
+}
+
+func TestParquetRepositoryInvalidRows(t *testing.T) {
+	ctx := context.Background()
+
+	// PassengerId is optional here, so it can hold nulls.
+	type row struct {
+		PassengerID *int64 `parquet:"PassengerId,optional"`
+		Name        string `parquet:"Name"`
+	}
+	one, zero, negative := int64(1), int64(0), int64(-3)
+	filePath := filepath.Join(t.TempDir(), "passengers.parquet")
+	assert.NoError(t, parquet.WriteFile(filePath, []row{
+		{PassengerID: &one, Name: "Valid"},
+		{Name: "Null ID"},
+		{PassengerID: &zero, Name: "Zero ID"},
+		{PassengerID: &negative, Name: "Negative ID"},
+	}))
+	repo, err := NewParquetRepository(filePath)
+	assert.NoError(t, err)
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 1)
+	assert.Equal(t, "Valid", passengers[0].Name)
+	version, err := repo.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version.Rows)
+	assert.Equal(t, 3, version.Invalid)
+
+	// A file without the column at all serves no passengers rather than passenger 0.
+	noIDs := filepath.Join(t.TempDir(), "no_ids.parquet")
+	assert.NoError(t, parquet.WriteFile(noIDs, []struct {
+		Name string `parquet:"Name"`
+	}{{Name: "Anonymous"}}))
+	repo, err = NewParquetRepository(noIDs)
+	assert.NoError(t, err)
+	version, err = repo.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version.Rows)
+	assert.Equal(t, 1, version.Invalid)
+	assert.ErrorIs(t, repo.Ping(ctx), ErrEmptyDataset)
+}
+
+func TestNDJSONRepository(t *testing.T) {
+	want := fileTestPassengers(t)
+	filePath := filepath.Join(t.TempDir(), "passengers.ndjson")
+	writeNDJSON(t, filePath, want,
+		"",
+		`{"passengerId": 4, "name": "Cabin Blank", "cabin": "", "embarked": ""}`,
+		`{"name": "No ID"}`,
+		`{"passengerId": "5"}`,
+		`not json`,
+	)
+
+	repo, err := NewNDJSONRepository(filePath)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	passengers, err := repo.GetAllPassengers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 4)
+	assert.Equal(t, want, passengers[:3])
+	assert.Nil(t, passengers[3].Cabin, "empty strings are treated as missing")
+	assert.Nil(t, passengers[3].Embarked)
+
+	version, err := repo.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, version.Rows)
+	assert.Equal(t, 3, version.Invalid)
+}
+
+func TestNDJSONPassengersStopsEarly(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "passengers.ndjson")
+	writeNDJSON(t, filePath, fileTestPassengers(t))
+	repo, err := NewNDJSONRepository(filePath)
+	assert.NoError(t, err)
+
+	var seen int
+	for _, err := range repo.Passengers(context.Background()) {
+		assert.NoError(t, err)
+		seen++
+		break
+	}
+	assert.Equal(t, 1, seen)
+}
+
+func TestFileRepositoryVersionMatchesCSV(t *testing.T) {
+	passengers := fileTestPassengers(t)
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "passengers.csv")
+	assert.NoError(t, os.WriteFile(csvPath, []byte(fileTestCSV), 0o644))
+	parquetPath := filepath.Join(dir, "passengers.parquet")
+	writeParquet(t, parquetPath, passengers)
+	ndjsonPath := filepath.Join(dir, "passengers.jsonl")
+	writeNDJSON(t, ndjsonPath, passengers)
+
+	ctx := context.Background()
+	var hashes []string
+	for _, path := range []string{csvPath, parquetPath, ndjsonPath} {
+		repo, err := OpenFile(path)
+		assert.NoError(t, err, path)
+		version, err := repo.Version(ctx)
+		assert.NoError(t, err, path)
+		assert.Equal(t, 3, version.Rows, path)
+		hashes = append(hashes, version.Hash)
+	}
+	assert.Equal(t, hashes[0], hashes[1], "parquet")
+	assert.Equal(t, hashes[0], hashes[2], "ndjson")
+}
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "passengers.CSV")
+	assert.NoError(t, os.WriteFile(csvPath, []byte(fileTestCSV), 0o644))
+	repo, err := OpenFile(csvPath)
+	assert.NoError(t, err)
+	assert.IsType(t, &CSVRepository{}, repo)
+
+	_, err = OpenFile(filepath.Join(dir, "passengers.xlsx"))
+	assert.ErrorContains(t, err, "valid extensions are: .csv, .jsonl, .ndjson, .parquet")
+
+	repo, err = OpenFile(filepath.Join(dir, "missing.parquet"))
+	assert.Error(t, err)
+	assert.Nil(t, repo)
+
+	repo, err = Open("file", func(v any) error {
+		v.(*FileConfig).File = csvPath
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Ping(context.Background()))
+}
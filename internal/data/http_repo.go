@@ -0,0 +1,323 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterSource("http", func(cfg HTTPConfig) (PassengerRepository, error) {
+		repo, err := NewHTTPRepository(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+}
+
+// HTTPConfig is the data.http section of the configuration.
+type HTTPConfig struct {
+	// URL is the http(s) address of the passenger file.
+	URL string `mapstructure:"url"`
+	// CacheFile is where the last good download is kept. Its extension selects
+	// the format, as for the file source.
+	CacheFile string `mapstructure:"cache_file"`
+	// RefreshInterval is how often the file is downloaded again, or zero to
+	// download it only at startup.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	Timeout         time.Duration `mapstructure:"timeout"`
+	// SHA256 is the expected hex digest of the file, if known in advance.
+	SHA256 string `mapstructure:"sha256"`
+	// SHA256URL is the address of a checksum file, in the format of sha256sum,
+	// fetched before each download. It is ignored when SHA256 is set.
+	SHA256URL string `mapstructure:"sha256_url"`
+	// MaxSize is the largest file accepted, in bytes. A larger download fails
+	// and leaves the cached copy in use.
+	MaxSize int64 `mapstructure:"max_size"`
+}
+
+const (
+	// defaultHTTPTimeout bounds each download when HTTPConfig.Timeout is not set.
+	defaultHTTPTimeout = 30 * time.Second
+	// defaultHTTPMaxSize bounds each download when HTTPConfig.MaxSize is not set.
+	defaultHTTPMaxSize = 100 << 20
+)
+
+// HTTPRepository serves a passenger file downloaded from a URL. The file is
+// kept in a local cache and downloaded again periodically, with If-None-Match
+// so that an unchanged file is not transferred. A download replaces the cache
+// only once its checksum and content are valid, so a failed download leaves
+// the last good copy in use.
+type HTTPRepository struct {
+	PassengerRepository // Reads the cache file.
+
+	cfg    HTTPConfig
+	client *http.Client
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	etag   string // ETag of the cached copy.
+	digest string // SHA-256 of the cached copy.
+}
+
+// NewHTTPRepository downloads the file at cfg.URL and starts refreshing it in
+// the background. If the download fails, a valid copy left in the cache by an
+// earlier run is served instead.
+func NewHTTPRepository(ctx context.Context, cfg HTTPConfig) (*HTTPRepository, error) {
+	if u, err := url.Parse(cfg.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("data source URL must be an http or https URL, got %q", cfg.URL)
+	}
+	if cfg.CacheFile == "" {
+		return nil, errors.New("data source cache file is not set")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHTTPTimeout
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultHTTPMaxSize
+	}
+	cfg.SHA256 = strings.ToLower(cfg.SHA256)
+	if err := os.MkdirAll(filepath.Dir(cfg.CacheFile), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create the data cache directory: %w", err)
+	}
+	r := &HTTPRepository{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+
+	if err := r.loadCache(); err != nil {
+		slog.WarnContext(ctx, "ignoring cached data file", "file", cfg.CacheFile, "error", err)
+	}
+	if err := r.Refresh(ctx); err != nil {
+		if r.digest == "" {
+			return nil, err
+		}
+		slog.WarnContext(ctx, "could not download data file, serving the cached copy", "url", cfg.URL, "file", cfg.CacheFile, "error", err)
+	}
+
+	repo, err := OpenFile(cfg.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+	r.PassengerRepository = repo
+
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.done = make(chan struct{})
+	go r.refreshLoop(ctx)
+	return r, nil
+}
+
+// loadCache picks up the copy downloaded by an earlier run, if there is one
+// and it still matches the configured checksum.
+func (r *HTTPRepository) loadCache() error {
+	digest, err := fileSHA256(r.cfg.CacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if r.cfg.SHA256 != "" && digest != r.cfg.SHA256 {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", digest, r.cfg.SHA256)
+	}
+	r.digest = digest
+	if etag, err := os.ReadFile(r.etagFile()); err == nil {
+		r.etag = strings.TrimSpace(string(etag))
+	}
+	return nil
+}
+
+// etagFile is where the ETag of the cached copy is kept across restarts.
+func (r *HTTPRepository) etagFile() string {
+	return r.cfg.CacheFile + ".etag"
+}
+
+// Refresh downloads the file if it has changed since the last download. On
+// failure the cached copy stays in use.
+func (r *HTTPRepository) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	want, err := r.expectedSHA256(ctx)
+	if err != nil {
+		return err
+	}
+	if want != "" && want == r.digest {
+		return nil // The cached copy is already the published one.
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.URL, nil)
+	if err != nil {
+		return err
+	}
+	// A cached copy with the wrong checksum must be downloaded again, even if unchanged.
+	if r.etag != "" && want == "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not download %s: %w", r.cfg.URL, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		slog.DebugContext(ctx, "data file unchanged", "url", r.cfg.URL, "etag", r.etag)
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("could not download %s: unexpected status %s", r.cfg.URL, resp.Status)
+	}
+
+	digest, size, err := r.replaceCache(resp.Body, want)
+	if err != nil {
+		return fmt.Errorf("could not download %s: %w", r.cfg.URL, err)
+	}
+	changed := digest != r.digest
+	r.digest = digest
+	r.etag = resp.Header.Get("ETag")
+	if r.etag != "" {
+		err = os.WriteFile(r.etagFile(), []byte(r.etag+"\n"), 0o644)
+	} else {
+		err = os.Remove(r.etagFile())
+	}
+	if err != nil && !os.IsNotExist(err) {
+		slog.WarnContext(ctx, "could not save the ETag of the data file", "file", r.etagFile(), "error", err)
+	}
+	if !changed {
+		slog.DebugContext(ctx, "data file unchanged", "url", r.cfg.URL, "sha256", digest)
+		return nil
+	}
+	slog.InfoContext(ctx, "downloaded data file", "url", r.cfg.URL, "file", r.cfg.CacheFile, "bytes", size, "sha256", digest)
+	return nil
+}
+
+// expectedSHA256 returns the checksum the download must have, or "" if none is configured.
+func (r *HTTPRepository) expectedSHA256(ctx context.Context) (string, error) {
+	if r.cfg.SHA256 != "" || r.cfg.SHA256URL == "" {
+		return r.cfg.SHA256, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.SHA256URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not download checksum %s: %w", r.cfg.SHA256URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not download checksum %s: unexpected status %s", r.cfg.SHA256URL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("could not download checksum %s: %w", r.cfg.SHA256URL, err)
+	}
+	// sha256sum prints the digest followed by the file name.
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %s is empty", r.cfg.SHA256URL)
+	}
+	digest := strings.ToLower(fields[0])
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+		return "", fmt.Errorf("checksum file %s does not hold a SHA-256 digest", r.cfg.SHA256URL)
+	}
+	return digest, nil
+}
+
+// replaceCache writes body next to the cache file and, once its checksum
+// matches want (when set) and it holds valid passengers, moves it over the
+// cache file. Readers of the previous copy keep reading it until they finish.
+// A body longer than the configured maximum size is rejected.
+func (r *HTTPRepository) replaceCache(body io.Reader, want string) (string, int64, error) {
+	dir, base := filepath.Split(r.cfg.CacheFile)
+	// The temporary file keeps the extension, so OpenFile reads it in the same format.
+	tmp, err := os.CreateTemp(dir, "."+base+".*"+filepath.Ext(base))
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	// One byte past the limit is enough to tell that the file is too large.
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(body, r.cfg.MaxSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if size > r.cfg.MaxSize {
+		return "", 0, fmt.Errorf("file exceeds the limit of %d bytes", r.cfg.MaxSize)
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	if want != "" && digest != want {
+		return "", 0, fmt.Errorf("checksum mismatch: got %s, want %s", digest, want)
+	}
+	if digest == r.digest {
+		// Servers that send no ETag return the same file again. Leaving the
+		// cache untouched spares recomputing the dataset version.
+		return digest, size, nil
+	}
+
+	repo, err := OpenFile(tmp.Name())
+	if err != nil {
+		return "", 0, err
+	}
+	defer repo.Close()
+	if err := repo.Ping(context.Background()); err != nil {
+		return "", 0, fmt.Errorf("invalid data file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.cfg.CacheFile); err != nil {
+		return "", 0, err
+	}
+	return digest, size, nil
+}
+
+func (r *HTTPRepository) refreshLoop(ctx context.Context) {
+	defer close(r.done)
+	if r.cfg.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "could not refresh data file, serving the cached copy", "url", r.cfg.URL, "error", err)
+			}
+		}
+	}
+}
+
+// Close stops the background refresh.
+func (r *HTTPRepository) Close() error {
+	r.cancel()
+	<-r.done
+	return r.PassengerRepository.Close()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,213 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const httpTestHeader = "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"
+
+// fileServer serves a mutable file with a strong ETag and counts the responses by status.
+type fileServer struct {
+	mu       sync.Mutex
+	content  string
+	status   int // Overrides the response when set.
+	statuses map[int]int
+}
+
+func (s *fileServer) set(content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content = content
+}
+
+func (s *fileServer) count(status int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statuses[status]
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.statuses == nil {
+		s.statuses = make(map[int]int)
+	}
+	status := http.StatusOK
+	etag := `"` + sha256Hex(s.content) + `"`
+	switch {
+	case s.status != 0:
+		status = s.status
+	case r.Header.Get("If-None-Match") == etag:
+		status = http.StatusNotModified
+	}
+	s.statuses[status]++
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		w.Write([]byte(s.content))
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func passengerNames(t *testing.T, repo PassengerRepository) []string {
+	passengers, err := repo.GetAllPassengers(context.Background())
+	assert.NoError(t, err)
+	var names []string
+	for _, p := range passengers {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestHTTPRepository(t *testing.T) {
+	files := &fileServer{content: httpTestHeader + "1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n"}
+	server := httptest.NewServer(files)
+	defer server.Close()
+	ctx := context.Background()
+
+	cfg := HTTPConfig{URL: server.URL + "/titanic.csv", CacheFile: filepath.Join(t.TempDir(), "cache", "titanic.csv")}
+	repo, err := NewHTTPRepository(ctx, cfg)
+	assert.NoError(t, err)
+	defer repo.Close()
+	assert.Equal(t, []string{"John Doe"}, passengerNames(t, repo))
+	v1, err := repo.Version(ctx)
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Refresh(ctx))
+	assert.Equal(t, 1, files.count(http.StatusNotModified), "an unchanged file is not downloaded again")
+
+	files.set(httpTestHeader + "2,0,3,Jane Roe,female,25,1,1,54321,200.0,,C\n")
+	assert.NoError(t, repo.Refresh(ctx))
+	assert.Equal(t, []string{"Jane Roe"}, passengerNames(t, repo))
+	v2, err := repo.Version(ctx)
+	assert.NoError(t, err)
+	assert.NotEqual(t, v1.Hash, v2.Hash)
+
+	// A server that sends no ETag returns the whole file, which must not be rewritten.
+	info, err := os.Stat(cfg.CacheFile)
+	assert.NoError(t, err)
+	noETag := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		files.mu.Lock()
+		defer files.mu.Unlock()
+		w.Write([]byte(files.content))
+	}))
+	defer noETag.Close()
+	repo.cfg.URL = noETag.URL
+	assert.NoError(t, repo.Refresh(ctx))
+	again, err := os.Stat(cfg.CacheFile)
+	assert.NoError(t, err)
+	assert.Equal(t, info.ModTime(), again.ModTime())
+	repo.cfg.URL = cfg.URL
+
+	// Downloads that fail, or are not valid passenger files, leave the cached copy in use.
+	files.set("not,a,passenger,file\n")
+	assert.ErrorContains(t, repo.Refresh(ctx), "invalid data file")
+	files.set(httpTestHeader + "3,1,2,Too Large,female,25,1,1,54321,200.0,,C\n")
+	repo.cfg.MaxSize = 64
+	assert.ErrorContains(t, repo.Refresh(ctx), "file exceeds the limit of 64 bytes")
+	repo.cfg.MaxSize = defaultHTTPMaxSize
+	files.mu.Lock()
+	files.status = http.StatusInternalServerError
+	files.mu.Unlock()
+	assert.ErrorContains(t, repo.Refresh(ctx), "unexpected status 500")
+	assert.Equal(t, []string{"Jane Roe"}, passengerNames(t, repo))
+	assert.NoError(t, repo.Ping(ctx))
+
+	entries, err := os.ReadDir(filepath.Dir(cfg.CacheFile))
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.Contains(t, []string{"titanic.csv", "titanic.csv.etag"}, entry.Name(), "no temporary files are left behind")
+	}
+}
+
+func TestHTTPRepositoryChecksum(t *testing.T) {
+	content := httpTestHeader + "1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n"
+	files := &fileServer{content: content}
+	server := httptest.NewServer(files)
+	defer server.Close()
+	ctx := context.Background()
+	cacheFile := filepath.Join(t.TempDir(), "titanic.csv")
+
+	_, err := NewHTTPRepository(ctx, HTTPConfig{URL: server.URL, CacheFile: cacheFile, SHA256: sha256Hex("other")})
+	assert.ErrorContains(t, err, "checksum mismatch")
+	_, err = os.Stat(cacheFile)
+	assert.True(t, os.IsNotExist(err), "a file with the wrong checksum is not cached")
+
+	repo, err := NewHTTPRepository(ctx, HTTPConfig{URL: server.URL, CacheFile: cacheFile, SHA256: sha256Hex(content)})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+
+	// The checksum can be published next to the file, in the format of sha256sum.
+	checksum := sha256Hex(content) + "  titanic.csv\n"
+	mux := http.NewServeMux()
+	mux.Handle("/titanic.csv", files)
+	mux.HandleFunc("/titanic.csv.sha256", func(w http.ResponseWriter, r *http.Request) {
+		files.mu.Lock()
+		defer files.mu.Unlock()
+		w.Write([]byte(checksum))
+	})
+	published := httptest.NewServer(mux)
+	defer published.Close()
+
+	cfg := HTTPConfig{URL: published.URL + "/titanic.csv", SHA256URL: published.URL + "/titanic.csv.sha256", CacheFile: cacheFile}
+	repo, err = NewHTTPRepository(ctx, cfg)
+	assert.NoError(t, err)
+	defer repo.Close()
+	downloads := files.count(http.StatusOK)
+	assert.NoError(t, repo.Refresh(ctx))
+	assert.Equal(t, downloads, files.count(http.StatusOK), "a cached copy matching the published checksum is kept")
+
+	// Until its checksum is published, a new file is not picked up.
+	files.set(httpTestHeader + "2,0,3,Jane Roe,female,25,1,1,54321,200.0,,C\n")
+	assert.NoError(t, repo.Refresh(ctx))
+	assert.Equal(t, []string{"John Doe"}, passengerNames(t, repo))
+	files.mu.Lock()
+	checksum = sha256Hex(files.content)
+	files.mu.Unlock()
+	assert.NoError(t, repo.Refresh(ctx))
+	assert.Equal(t, []string{"Jane Roe"}, passengerNames(t, repo))
+}
+
+func TestHTTPRepositoryFallsBackToCache(t *testing.T) {
+	files := &fileServer{content: httpTestHeader + "1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n"}
+	server := httptest.NewServer(files)
+	ctx := context.Background()
+	cfg := HTTPConfig{URL: server.URL, CacheFile: filepath.Join(t.TempDir(), "titanic.csv")}
+
+	repo, err := NewHTTPRepository(ctx, cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+
+	// After a restart the saved ETag avoids downloading the file again.
+	repo, err = NewHTTPRepository(ctx, cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+	assert.Equal(t, 1, files.count(http.StatusNotModified))
+
+	server.Close()
+	repo, err = NewHTTPRepository(ctx, cfg)
+	assert.NoError(t, err, "the cached copy is served while the server is down")
+	assert.Equal(t, []string{"John Doe"}, passengerNames(t, repo))
+	assert.NoError(t, repo.Close())
+
+	cfg.CacheFile = filepath.Join(t.TempDir(), "titanic.csv")
+	_, err = NewHTTPRepository(ctx, cfg)
+	assert.Error(t, err, "without a cached copy the source cannot start")
+
+	_, err = NewHTTPRepository(ctx, HTTPConfig{URL: "ftp://example.com/titanic.csv", CacheFile: cfg.CacheFile})
+	assert.ErrorContains(t, err, "http or https URL")
+}
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// ImportMode controls how imported records are merged with the existing dataset.
+type ImportMode string
+
+const (
+	// ImportReplace discards the current dataset and loads the imported records in its place.
+	ImportReplace ImportMode = "replace"
+	// ImportUpsert inserts new passengers and overwrites existing ones with the same ID.
+	ImportUpsert ImportMode = "upsert"
+	// ImportAppendOnly inserts new passengers and rejects records whose ID already exists.
+	ImportAppendOnly ImportMode = "append-only"
+)
+
+// ParseImportMode validates a mode name supplied by a client.
+func ParseImportMode(s string) (ImportMode, error) {
+	switch mode := ImportMode(s); mode {
+	case ImportReplace, ImportUpsert, ImportAppendOnly:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid import mode %q, valid modes are: %s, %s, %s", s, ImportReplace, ImportUpsert, ImportAppendOnly)
+}
+
+// ImportRecord is a validated passenger together with the CSV line it came from.
+type ImportRecord struct {
+	Line      int
+	Passenger model.Passenger
+}
+
+// Importer is implemented by repositories that can load new data. An import is
+// atomic: either every accepted record is applied or none is.
+type Importer interface {
+	Import(ctx context.Context, mode ImportMode, records []ImportRecord) (model.ImportSummary, error)
+}
+
+// ErrImportNotSupported is returned when the configured data source is read-only.
+var ErrImportNotSupported = errors.New("the configured data source does not support imports")
+
+// csvHeader is the column layout of the Titanic CSV format.
+var csvHeader = []string{"PassengerId", "Survived", "Pclass", "Name", "Sex", "Age", "SibSp", "Parch", "Ticket", "Fare", "Cabin", "Embarked"}
+
+// ParseImportCSV reads a Titanic-format CSV and validates every row. Valid rows are
+// returned as records; each invalid row produces an ImportError naming its line.
+// Rows repeating an earlier PassengerId are rejected. An error is returned only
+// when the file as a whole is unusable, such as a missing or wrong header.
+func ParseImportCSV(r io.Reader) ([]ImportRecord, []model.ImportError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Column counts are checked per row below.
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read header: %w", err)
+	}
+	if len(header) != len(csvHeader) {
+		return nil, nil, fmt.Errorf("expected header %s", strings.Join(csvHeader, ","))
+	}
+	for i, name := range header {
+		if !strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), csvHeader[i]) {
+			return nil, nil, fmt.Errorf("expected header %s", strings.Join(csvHeader, ","))
+		}
+	}
+
+	var records []ImportRecord
+	var rejected []model.ImportError
+	seen := make(map[int]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rejected = append(rejected, model.ImportError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		p, err := validateRecord(record)
+		if err != nil {
+			rejected = append(rejected, model.ImportError{Line: line, Message: err.Error()})
+			continue
+		}
+		if first, dup := seen[p.PassengerID]; dup {
+			rejected = append(rejected, model.ImportError{
+				Line:    line,
+				Message: fmt.Sprintf("PassengerId %d already appears on line %d", p.PassengerID, first),
+			})
+			continue
+		}
+		seen[p.PassengerID] = line
+		records = append(records, ImportRecord{Line: line, Passenger: p})
+	}
+	return records, rejected, nil
+}
+
+// validateRecord converts a CSV record like recordToPassenger does, but rejects
+// any value that recordToPassenger would silently drop or zero.
+func validateRecord(record []string) (model.Passenger, error) {
+	if len(record) != len(csvHeader) {
+		return model.Passenger{}, fmt.Errorf("expected %d columns, got %d", len(csvHeader), len(record))
+	}
+	for i := range record {
+		record[i] = strings.TrimSpace(record[i])
+	}
+
+	var p model.Passenger
+	var err error
+	if p.PassengerID, err = strconv.Atoi(record[0]); err != nil || p.PassengerID <= 0 {
+		return p, fmt.Errorf("PassengerId must be a positive integer, got %q", record[0])
+	}
+	if p.Survived, err = strconv.Atoi(record[1]); err != nil || (p.Survived != 0 && p.Survived != 1) {
+		return p, fmt.Errorf("Survived must be 0 or 1, got %q", record[1])
+	}
+	if p.Pclass, err = strconv.Atoi(record[2]); err != nil || p.Pclass < 1 || p.Pclass > 3 {
+		return p, fmt.Errorf("Pclass must be 1, 2 or 3, got %q", record[2])
+	}
+	if p.Name = record[3]; p.Name == "" {
+		return p, errors.New("Name must not be empty")
+	}
+	if p.Sex = record[4]; p.Sex != "male" && p.Sex != "female" {
+		return p, fmt.Errorf("Sex must be male or female, got %q", record[4])
+	}
+	if p.Age, err = parseOptionalFloat(record[5]); err != nil {
+		return p, fmt.Errorf("Age %v", err)
+	}
+	if p.SibSp, err = strconv.Atoi(record[6]); err != nil || p.SibSp < 0 {
+		return p, fmt.Errorf("SibSp must be a non-negative integer, got %q", record[6])
+	}
+	if p.Parch, err = strconv.Atoi(record[7]); err != nil || p.Parch < 0 {
+		return p, fmt.Errorf("Parch must be a non-negative integer, got %q", record[7])
+	}
+	p.Ticket = record[8]
+	if p.Fare, err = parseOptionalFloat(record[9]); err != nil {
+		return p, fmt.Errorf("Fare %v", err)
+	}
+	if cabin := record[10]; cabin != "" {
+		p.Cabin = &cabin
+	}
+	if embarked := record[11]; embarked != "" {
+		if embarked != "C" && embarked != "Q" && embarked != "S" {
+			return p, fmt.Errorf("Embarked must be C, Q, S or empty, got %q", embarked)
+		}
+		p.Embarked = &embarked
+	}
+	return p, nil
+}
+
+// parseOptionalFloat parses an empty string as nil and anything else as a non-negative number.
+func parseOptionalFloat(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("must be empty or a non-negative number, got %q", s)
+	}
+	return &v, nil
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const importHeader = "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"
+
+func TestParseImportCSV(t *testing.T) {
+	content := importHeader +
+		"1,1,1,\"Doe, Mr. John\",male,30,0,0,12345,100.0,C123,S\n" +
+		"2,2,3,Jane Doe,female,25,1,1,54321,200.0,,C\n" +
+		"3,0,3,Sam Roe,male,,0,0,999,,,\n" +
+		"1,0,2,Duplicate,male,1,0,0,1,1,,S\n" +
+		"4,0,3,Short Row\n" +
+		"5,0,4,Bad Class,male,1,0,0,1,1,,S\n" +
+		"6,0,3,Bad Port,female,1,0,0,1,1,,X\n"
+
+	records, rejected, err := ParseImportCSV(strings.NewReader(content))
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, 2, records[0].Line)
+	assert.Equal(t, "Doe, Mr. John", records[0].Passenger.Name)
+	assert.Equal(t, 4, records[1].Line)
+	assert.Nil(t, records[1].Passenger.Age)
+	assert.Nil(t, records[1].Passenger.Embarked)
+
+	lines := make([]int, len(rejected))
+	for i, e := range rejected {
+		lines[i] = e.Line
+	}
+	assert.Equal(t, []int{3, 5, 6, 7, 8}, lines)
+	assert.Contains(t, rejected[0].Message, "Survived")
+	assert.Contains(t, rejected[1].Message, "line 2")
+	assert.Contains(t, rejected[2].Message, "columns")
+}
+
+func TestParseImportCSV_BadHeader(t *testing.T) {
+	_, _, err := ParseImportCSV(strings.NewReader("id,name\n1,John\n"))
+	assert.Error(t, err)
+
+	_, _, err = ParseImportCSV(strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestParseImportMode(t *testing.T) {
+	mode, err := ParseImportMode("append-only")
+	assert.NoError(t, err)
+	assert.Equal(t, ImportAppendOnly, mode)
+
+	_, err = ParseImportMode("merge")
+	assert.Error(t, err)
+}
+
+// newTempSQLiteRepository creates a database holding passengers 1 and 2.
+func newTempSQLiteRepository(t *testing.T) *SQLiteRepository {
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "titanic.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { repo.db.Close() })
+
+	_, err = repo.db.Exec(`CREATE TABLE passengers (
+		PassengerId INTEGER PRIMARY KEY, Survived INTEGER, Pclass INTEGER, Name TEXT, Sex TEXT, Age REAL,
+		SibSp INTEGER, Parch INTEGER, Ticket TEXT, Fare REAL, Cabin TEXT, Embarked TEXT);
+	INSERT INTO passengers VALUES (1, 0, 3, 'Old One', 'male', 22, 1, 0, 'A/5', 7.25, NULL, 'S');
+	INSERT INTO passengers VALUES (2, 1, 1, 'Old Two', 'female', 38, 1, 0, 'PC', 71.28, 'C85', 'C');`)
+	assert.NoError(t, err)
+	return repo
+}
+
+func parseRecords(t *testing.T, rows string) []ImportRecord {
+	records, rejected, err := ParseImportCSV(strings.NewReader(importHeader + rows))
+	assert.NoError(t, err)
+	assert.Empty(t, rejected)
+	return records
+}
+
+func TestSQLiteImport(t *testing.T) {
+	rows := "2,0,2,New Two,female,39,0,0,PC,70,,S\n3,1,2,New Three,male,,0,0,X,10,,Q\n"
+
+	for _, tc := range []struct {
+		mode                        ImportMode
+		inserted, updated, rejected int
+		wantIDs                     []int
+		wantNameOfTwo               string
+	}{
+		{ImportReplace, 2, 0, 0, []int{2, 3}, "New Two"},
+		{ImportUpsert, 1, 1, 0, []int{1, 2, 3}, "New Two"},
+		{ImportAppendOnly, 1, 0, 1, []int{1, 2, 3}, "Old Two"},
+	} {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			repo := newTempSQLiteRepository(t)
+			before, err := repo.Version(context.Background())
+			assert.NoError(t, err)
+
+			summary, err := repo.Import(context.Background(), tc.mode, parseRecords(t, rows))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.inserted, summary.Inserted)
+			assert.Equal(t, tc.updated, summary.Updated)
+			assert.Equal(t, tc.rejected, summary.Rejected)
+			if tc.rejected > 0 {
+				assert.Equal(t, []model.ImportError{{Line: 2, Message: "PassengerId 2 already exists"}}, summary.Errors)
+			}
+
+			passengers, err := repo.GetAllPassengers(context.Background())
+			assert.NoError(t, err)
+			var ids []int
+			for _, p := range passengers {
+				ids = append(ids, p.PassengerID)
+				if p.PassengerID == 2 {
+					assert.Equal(t, tc.wantNameOfTwo, p.Name)
+				}
+			}
+			assert.Equal(t, tc.wantIDs, ids)
+
+			after, err := repo.Version(context.Background())
+			assert.NoError(t, err)
+			assert.NotEqual(t, before.Hash, after.Hash)
+		})
+	}
+}
+
+func TestSQLiteImportRollsBack(t *testing.T) {
+	repo := newTempSQLiteRepository(t)
+	_, err := repo.db.Exec("CREATE TRIGGER reject_three BEFORE INSERT ON passengers WHEN NEW.PassengerId = 3 " +
+		"BEGIN SELECT RAISE(ABORT, 'no'); END")
+	assert.NoError(t, err)
+
+	_, err = repo.Import(context.Background(), ImportReplace, parseRecords(t, "3,1,2,New Three,male,,0,0,X,10,,Q\n"))
+
+	assert.Error(t, err)
+	passengers, err := repo.GetAllPassengers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2, "a failed import must leave the dataset untouched")
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownSource is returned by Open for a data source that was never registered.
+var ErrUnknownSource = errors.New("unknown data source")
+
+// Factory opens a repository. decode fills a backend's settings struct from its
+// section of the configuration.
+type Factory func(decode func(v any) error) (PassengerRepository, error)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]Factory)
+)
+
+// Register makes a data source available under name. Backends call it from an
+// init function, so a package outside internal/data can add one by being
+// imported. Register panics if name is empty or already taken.
+func Register(name string, factory Factory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	if name == "" || factory == nil {
+		panic("data: Register needs a name and a factory")
+	}
+	if _, dup := sources[name]; dup {
+		panic("data: Register called twice for source " + name)
+	}
+	sources[name] = factory
+}
+
+// RegisterSource registers a data source whose settings decode into a C.
+func RegisterSource[C any](name string, open func(cfg C) (PassengerRepository, error)) {
+	Register(name, func(decode func(v any) error) (PassengerRepository, error) {
+		var cfg C
+		if err := decode(&cfg); err != nil {
+			return nil, fmt.Errorf("invalid settings for data source %q: %w", name, err)
+		}
+		return open(cfg)
+	})
+}
+
+// Sources lists the registered data sources in alphabetical order.
+func Sources() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open builds the repository of the named data source, passing decode to its factory.
+func Open(name string, decode func(v any) error) (PassengerRepository, error) {
+	sourcesMu.RLock()
+	factory, ok := sources[name]
+	sourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q, available sources are: %s", ErrUnknownSource, name, strings.Join(Sources(), ", "))
+	}
+	return factory(decode)
+}
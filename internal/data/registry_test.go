@@ -0,0 +1,53 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	filePath := createTempCSV(t, "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n")
+	defer os.Remove(filePath)
+
+	repo, err := Open("csv", func(v any) error {
+		v.(*CSVConfig).File = filePath
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &CSVRepository{}, repo)
+
+	_, err = Open("csv", func(v any) error { return errors.New("bad yaml") })
+	assert.ErrorContains(t, err, `invalid settings for data source "csv": bad yaml`)
+
+	_, err = Open("mongo", func(v any) error { return nil })
+	assert.ErrorIs(t, err, ErrUnknownSource)
+	assert.ErrorContains(t, err, "available sources are: csv, embedded, file, http, ndjson, parquet, postgres, sqlite")
+}
+
+func TestRegister(t *testing.T) {
+	type fakeConfig struct{ Rows int }
+	var got fakeConfig
+	RegisterSource("fake", func(cfg fakeConfig) (PassengerRepository, error) {
+		got = cfg
+		return &CSVRepository{}, nil
+	})
+	defer func() {
+		sourcesMu.Lock()
+		delete(sources, "fake")
+		sourcesMu.Unlock()
+	}()
+
+	assert.Contains(t, Sources(), "fake")
+	_, err := Open("fake", func(v any) error {
+		v.(*fakeConfig).Rows = 3
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got.Rows)
+
+	assert.Panics(t, func() { Register("fake", func(func(any) error) (PassengerRepository, error) { return nil, nil }) })
+	assert.Panics(t, func() { Register("", nil) })
+}
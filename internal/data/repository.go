@@ -1,10 +1,66 @@
 package data
 
-import "github.com/dhope-nagesh/titanic-go-service/internal/model"
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
 
 // PassengerRepository defines the interface for data access.
 type PassengerRepository interface {
-	GetAllPassengers() ([]model.Passenger, error)
-	GetPassengerByID(id int) (*model.Passenger, error)
-	GetFares() ([]float64, error)
+	GetAllPassengers(ctx context.Context) ([]model.Passenger, error)
+	GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error)
+	// GetPassengersByIDs returns the passengers matching any of the given IDs in a
+	// single lookup. IDs with no matching passenger are simply absent from the result.
+	GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error)
+	// GetFares returns the fare of every passenger that has one. The order is
+	// unspecified, and the caller owns the slice.
+	GetFares(ctx context.Context) ([]float64, error)
+	// Passengers streams every passenger as it is read from the underlying storage,
+	// so callers can process large datasets without holding them in memory.
+	// Iteration stops at the first error or when ctx is cancelled.
+	Passengers(ctx context.Context) iter.Seq2[model.Passenger, error]
+	// Version describes the dataset currently being served. It is cheap to call
+	// repeatedly: implementations recompute it only when the data changes.
+	Version(ctx context.Context) (DatasetVersion, error)
+	// Ping checks that the data source is reachable and holds passengers.
+	Ping(ctx context.Context) error
+	// Close releases the resources held by the repository. It must not be used afterwards.
+	Close() error
+}
+
+// DatasetVersion identifies the content currently served by a repository.
+type DatasetVersion struct {
+	// Hash is a hex SHA-256 digest over every record. Backends holding the same
+	// passengers report the same hash, whatever the storage format.
+	Hash string
+	// ModTime is when the underlying data last changed, or zero if unknown.
+	ModTime time.Time
+	// Rows is the number of passengers in the dataset.
+	Rows int
+	// Invalid is the number of stored records that could not be parsed and are skipped.
+	Invalid int
+	// LoadedAt is when this version was last read from the data source.
+	LoadedAt time.Time
+}
+
+// ErrPassengerNotFound is returned by GetPassengerByID when no passenger has the ID.
+var ErrPassengerNotFound = errors.New("passenger not found")
+
+// ErrEmptyDataset is returned by Ping when the data source holds no passengers.
+var ErrEmptyDataset = errors.New("the dataset contains no passengers")
+
+// collect drains a passenger iterator into a slice.
+func collect(seq iter.Seq2[model.Passenger, error]) ([]model.Passenger, error) {
+	var passengers []model.Passenger
+	for p, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		passengers = append(passengers, p)
+	}
+	return passengers, nil
 }
@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// passengerColumns lists the passengers table columns in the order scanned by scanPassenger.
+const passengerColumns = "PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, Parch, Ticket, Fare, Cabin, Embarked"
+
+// sqlQueries implements the queries shared by the SQL repositories, in the
+// syntax of their dialect.
+type sqlQueries struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPassenger(s scanner) (model.Passenger, error) {
+	var p model.Passenger
+	err := s.Scan(&p.PassengerID, &p.Survived, &p.Pclass, &p.Name, &p.Sex, &p.Age, &p.SibSp, &p.Parch, &p.Ticket, &p.Fare, &p.Cabin, &p.Embarked)
+	return p, err
+}
+
+// createSchema creates the passengers table unless it exists.
+func (q sqlQueries) createSchema(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, q.dialect.schema)
+	return err
+}
+
+// passengers streams the table in PassengerId order, counting the rows skipped as invalid in
+// *invalid when it is not nil.
+func (q sqlQueries) passengers(ctx context.Context, invalid *int) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		// The dataset hash covers row order, which without ORDER BY changes with
+		// the physical layout, e.g. after an update or a VACUUM.
+		rows, err := q.db.QueryContext(ctx, "SELECT "+passengerColumns+" FROM passengers ORDER BY PassengerId")
+		if err != nil {
+			yield(model.Passenger{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanPassenger(rows)
+			if err != nil {
+				slog.WarnContext(ctx, "skipping invalid passenger row", "table", "passengers", "dialect", q.dialect.name, "error", err)
+				if invalid != nil {
+					*invalid++
+				}
+				continue
+			}
+			if !yield(p, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(model.Passenger{}, err)
+		}
+	}
+}
+
+func (q sqlQueries) passengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	row := q.db.QueryRowContext(ctx, q.dialect.rebind("SELECT "+passengerColumns+" FROM passengers WHERE PassengerId = ?"), id)
+	p, err := scanPassenger(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPassengerNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// passengersByIDs fetches all requested passengers with a single IN query.
+func (q sqlQueries) passengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := q.db.QueryContext(ctx, q.dialect.rebind("SELECT "+passengerColumns+" FROM passengers WHERE PassengerId IN ("+placeholders+")"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var passengers []model.Passenger
+	for rows.Next() {
+		p, err := scanPassenger(rows)
+		if err != nil {
+			slog.WarnContext(ctx, "skipping invalid passenger row", "table", "passengers", "dialect", q.dialect.name, "error", err)
+			continue
+		}
+		passengers = append(passengers, p)
+	}
+	return passengers, rows.Err()
+}
+
+func (q sqlQueries) fares(ctx context.Context) ([]float64, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT Fare FROM passengers WHERE Fare IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fares []float64
+	for rows.Next() {
+		var fare float64
+		if err := rows.Scan(&fare); err != nil {
+			slog.WarnContext(ctx, "skipping invalid fare", "table", "passengers", "dialect", q.dialect.name, "error", err)
+			continue
+		}
+		fares = append(fares, fare)
+	}
+	return fares, rows.Err()
+}
+
+// ping checks the connection and that the passengers table has rows.
+func (q sqlQueries) ping(ctx context.Context) error {
+	if err := q.db.PingContext(ctx); err != nil {
+		return err
+	}
+	var exists bool
+	if err := q.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM passengers)").Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEmptyDataset
+	}
+	return nil
+}
+
+// importRecords applies the records in a single transaction, so a failed import
+// leaves the dataset untouched. In append-only mode records with an existing ID
+// are rejected rather than failing the whole import.
+func (q sqlQueries) importRecords(ctx context.Context, mode ImportMode, records []ImportRecord) (model.ImportSummary, error) {
+	summary := model.ImportSummary{Mode: string(mode), Errors: []model.ImportError{}}
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback()
+
+	if mode == ImportReplace {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM passengers"); err != nil {
+			return summary, err
+		}
+	}
+
+	insert, err := tx.PrepareContext(ctx, q.dialect.rebind("INSERT INTO passengers("+passengerColumns+") "+
+		"VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"))
+	if err != nil {
+		return summary, err
+	}
+	defer insert.Close()
+
+	update, err := tx.PrepareContext(ctx, q.dialect.rebind("UPDATE passengers SET Survived = ?, Pclass = ?, Name = ?, Sex = ?, Age = ?, "+
+		"SibSp = ?, Parch = ?, Ticket = ?, Fare = ?, Cabin = ?, Embarked = ? WHERE PassengerId = ?"))
+	if err != nil {
+		return summary, err
+	}
+	defer update.Close()
+
+	exists := q.dialect.rebind("SELECT EXISTS(SELECT 1 FROM passengers WHERE PassengerId = ?)")
+	for _, rec := range records {
+		p := rec.Passenger
+		if mode != ImportReplace {
+			var found bool
+			if err := tx.QueryRowContext(ctx, exists, p.PassengerID).Scan(&found); err != nil {
+				return summary, err
+			}
+			if found && mode == ImportAppendOnly {
+				summary.Rejected++
+				summary.Errors = append(summary.Errors, model.ImportError{
+					Line:    rec.Line,
+					Message: fmt.Sprintf("PassengerId %d already exists", p.PassengerID),
+				})
+				continue
+			}
+			if found {
+				_, err := update.ExecContext(ctx, p.Survived, p.Pclass, p.Name, p.Sex, p.Age, p.SibSp, p.Parch, p.Ticket,
+					p.Fare, p.Cabin, p.Embarked, p.PassengerID)
+				if err != nil {
+					return summary, fmt.Errorf("line %d: %w", rec.Line, err)
+				}
+				summary.Updated++
+				continue
+			}
+		}
+		_, err := insert.ExecContext(ctx, p.PassengerID, p.Survived, p.Pclass, p.Name, p.Sex, p.Age, p.SibSp, p.Parch,
+			p.Ticket, p.Fare, p.Cabin, p.Embarked)
+		if err != nil {
+			return summary, fmt.Errorf("line %d: %w", rec.Line, err)
+		}
+		summary.Inserted++
+	}
+
+	return summary, tx.Commit()
+}
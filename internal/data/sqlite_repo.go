@@ -1,16 +1,35 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"github.com/dhope-nagesh/titanic-go-service/internal/filestamp"
 	"github.com/dhope-nagesh/titanic-go-service/internal/model"
-	"log"
+	"iter"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+func init() {
+	RegisterSource("sqlite", func(cfg SQLiteConfig) (PassengerRepository, error) {
+		repo, err := NewSQLiteRepository(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+}
+
+// SQLiteConfig is the data.sqlite section of the configuration.
+type SQLiteConfig struct {
+	File string `mapstructure:"file"`
+}
+
 type SQLiteRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	path    string
+	version versionCache
 }
 
 func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
@@ -21,59 +40,74 @@ func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
 	if err = db.Ping(); err != nil {
 		return nil, err
 	}
-	return &SQLiteRepository{db: db}, nil
+	return &SQLiteRepository{db: db, path: dbPath}, nil
 }
 
-func (r *SQLiteRepository) GetAllPassengers() ([]model.Passenger, error) {
-	rows, err := r.db.Query("SELECT PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, Parch, " +
-		"Ticket, Fare, Cabin, Embarked FROM passengers")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func (r *SQLiteRepository) queries() sqlQueries {
+	return sqlQueries{db: r.db, dialect: sqliteDialect}
+}
 
-	var passengers []model.Passenger
-	for rows.Next() {
-		var p model.Passenger
-		err := rows.Scan(&p.PassengerID, &p.Survived, &p.Pclass, &p.Name, &p.Sex, &p.Age, &p.SibSp, &p.Parch, &p.Ticket, &p.Fare, &p.Cabin, &p.Embarked)
-		if err != nil {
-			log.Printf("Error scanning passenger: %v", err)
-			continue
-		}
-		passengers = append(passengers, p)
-	}
-	return passengers, nil
+// CreateSchema creates the passengers table unless it exists.
+func (r *SQLiteRepository) CreateSchema(ctx context.Context) error {
+	return r.queries().createSchema(ctx)
 }
 
-func (r *SQLiteRepository) GetPassengerByID(id int) (*model.Passenger, error) {
-	row := r.db.QueryRow("SELECT PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, Parch, Ticket, Fare, "+
-		"Cabin, Embarked FROM passengers WHERE PassengerId = ?", id)
+func (r *SQLiteRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	return collect(r.Passengers(ctx))
+}
 
-	var p model.Passenger
-	err := row.Scan(&p.PassengerID, &p.Survived, &p.Pclass, &p.Name, &p.Sex, &p.Age, &p.SibSp, &p.Parch, &p.Ticket, &p.Fare, &p.Cabin, &p.Embarked)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("passenger not found")
+// Passengers streams passengers straight from the result set without buffering them.
+func (r *SQLiteRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return r.queries().passengers(ctx, nil)
+}
+
+func (r *SQLiteRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	return r.queries().passengerByID(ctx, id)
+}
+
+// GetPassengersByIDs fetches all requested passengers with a single IN query.
+func (r *SQLiteRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	return r.queries().passengersByIDs(ctx, ids)
+}
+
+func (r *SQLiteRepository) GetFares(ctx context.Context) ([]float64, error) {
+	return r.queries().fares(ctx)
+}
+
+// Version hashes the passengers table. The result is cached until the database
+// file or its write-ahead log changes on disk.
+func (r *SQLiteRepository) Version(ctx context.Context) (DatasetVersion, error) {
+	var stamp string
+	var modTime time.Time
+	if r.path != "" {
+		var err error
+		if stamp, modTime, err = filestamp.Of(r.path, r.path+"-wal"); err != nil {
+			return DatasetVersion{}, err
 		}
-		return nil, err
 	}
-	return &p, nil
+	return r.version.get(stamp, func() (DatasetVersion, error) {
+		var invalid int
+		hash, rows, err := hashPassengers(r.queries().passengers(ctx, &invalid))
+		return DatasetVersion{Hash: hash, ModTime: modTime, Rows: rows, Invalid: invalid}, err
+	})
 }
 
-func (r *SQLiteRepository) GetFares() ([]float64, error) {
-	rows, err := r.db.Query("SELECT Fare FROM passengers WHERE Fare IS NOT NULL")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// Ping checks the connection and that the passengers table has rows.
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	return r.queries().ping(ctx)
+}
 
-	var fares []float64
-	for rows.Next() {
-		var fare float64
-		if err := rows.Scan(&fare); err != nil {
-			continue
-		}
-		fares = append(fares, fare)
+// Close closes the database handle.
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// Import applies the records with importRecords and refreshes the dataset version.
+func (r *SQLiteRepository) Import(ctx context.Context, mode ImportMode, records []ImportRecord) (model.ImportSummary, error) {
+	summary, err := r.queries().importRecords(ctx, mode, records)
+	if err != nil {
+		return summary, err
 	}
-	return fares, nil
+	r.version.invalidate()
+	return summary, nil
 }
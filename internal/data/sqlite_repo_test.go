@@ -1,8 +1,12 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -21,12 +25,12 @@ func TestGetAllPassengers(t *testing.T) {
 	db, mock := setupMockDB(t)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, Parch, Ticket, Fare, Cabin, Embarked FROM passengers").
+	mock.ExpectQuery("SELECT PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, Parch, Ticket, Fare, Cabin, Embarked FROM passengers ORDER BY PassengerId").
 		WillReturnRows(sqlmock.NewRows([]string{"PassengerId", "Survived", "Pclass", "Name", "Sex", "Age", "SibSp", "Parch", "Ticket", "Fare", "Cabin", "Embarked"}).
 			AddRow(1, 1, 1, "John Doe", "male", 30, 0, 0, "12345", 100.0, "C123", "S"))
 
 	repo := &SQLiteRepository{db: db}
-	passengers, err := repo.GetAllPassengers()
+	passengers, err := repo.GetAllPassengers(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, passengers, 1)
@@ -44,7 +48,7 @@ func TestGetPassengerByID(t *testing.T) {
 			AddRow(1, 1, 1, "John Doe", "male", 30, 0, 0, "12345", 100.0, "C123", "S"))
 
 	repo := &SQLiteRepository{db: db}
-	passenger, err := repo.GetPassengerByID(1)
+	passenger, err := repo.GetPassengerByID(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, passenger)
@@ -62,10 +66,82 @@ func TestGetFares(t *testing.T) {
 			AddRow(200.0))
 
 	repo := &SQLiteRepository{db: db}
-	fares, err := repo.GetFares()
+	fares, err := repo.GetFares(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, fares, 2)
 	assert.Equal(t, 100.0, fares[0])
 	assert.Equal(t, 200.0, fares[1])
 }
+
+func TestGetPassengersByIDs(t *testing.T) {
+	// Mock database setup
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT PassengerId, Survived, Pclass, Name, Sex, Age, SibSp, Parch, Ticket, Fare, Cabin, Embarked FROM passengers WHERE PassengerId IN (?, ?, ?)")).
+		WithArgs(1, 2, 999).
+		WillReturnRows(sqlmock.NewRows([]string{"PassengerId", "Survived", "Pclass", "Name", "Sex", "Age", "SibSp", "Parch", "Ticket", "Fare", "Cabin", "Embarked"}).
+			AddRow(1, 1, 1, "John Doe", "male", 30, 0, 0, "12345", 100.0, "C123", "S").
+			AddRow(2, 0, 3, "Jane Doe", "female", nil, 1, 1, "54321", 200.0, nil, "C"))
+
+	repo := &SQLiteRepository{db: db}
+	passengers, err := repo.GetPassengersByIDs(context.Background(), []int{1, 2, 999})
+
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+	assert.Equal(t, "Jane Doe", passengers[1].Name)
+	assert.Nil(t, passengers[1].Age)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLiteClose(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectClose()
+
+	repo := &SQLiteRepository{db: db}
+	assert.NoError(t, repo.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLitePing(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM passengers)")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM passengers)")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	repo := &SQLiteRepository{db: db}
+	assert.NoError(t, repo.Ping(context.Background()))
+	assert.ErrorIs(t, repo.Ping(context.Background()), ErrEmptyDataset)
+}
+
+func TestSQLiteVersionFollowsPassengerID(t *testing.T) {
+	// Without a primary key SQLite returns rows in insertion order, so the rows
+	// come back sorted only if the query asks for it.
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "titanic.db"))
+	assert.NoError(t, err)
+	defer repo.Close()
+	_, err = repo.db.Exec(`CREATE TABLE passengers (
+		PassengerId INTEGER, Survived INTEGER, Pclass INTEGER, Name TEXT, Sex TEXT, Age REAL,
+		SibSp INTEGER, Parch INTEGER, Ticket TEXT, Fare REAL, Cabin TEXT, Embarked TEXT);
+	INSERT INTO passengers VALUES (2, 1, 1, 'Two', 'female', 38, 1, 0, 'PC', 71.28, 'C85', 'C');
+	INSERT INTO passengers VALUES (1, 0, 3, 'One', 'male', 22, 1, 0, 'A/5', 7.25, NULL, 'S');`)
+	assert.NoError(t, err)
+
+	passengers, err := repo.GetAllPassengers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, passengers[0].PassengerID)
+
+	csvPath := createTempCSV(t, "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"+
+		"1,0,3,One,male,22,1,0,A/5,7.25,,S\n2,1,1,Two,female,38,1,0,PC,71.28,C85,C\n")
+	defer os.Remove(csvPath)
+	csvRepo, err := NewCSVRepository(csvPath)
+	assert.NoError(t, err)
+	want, err := csvRepo.Version(context.Background())
+	assert.NoError(t, err)
+	got, err := repo.Version(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, want.Hash, got.Hash, "a CSV sorted by ID and the table hash alike")
+}
@@ -0,0 +1,62 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// hashPassengers computes the content hash of a dataset by digesting the JSON
+// encoding of each passenger in order.
+func hashPassengers(seq iter.Seq2[model.Passenger, error]) (string, int, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	rows := 0
+	for p, err := range seq {
+		if err != nil {
+			return "", 0, err
+		}
+		if err := enc.Encode(p); err != nil {
+			return "", 0, err
+		}
+		rows++
+	}
+	return hex.EncodeToString(h.Sum(nil)), rows, nil
+}
+
+// versionCache remembers the last computed DatasetVersion for as long as the
+// files backing it are unchanged.
+type versionCache struct {
+	mu      sync.Mutex
+	stamp   string
+	version DatasetVersion
+}
+
+// get returns the cached version if stamp matches, or calls compute and caches its result.
+// An empty stamp disables caching.
+func (c *versionCache) get(stamp string, compute func() (DatasetVersion, error)) (DatasetVersion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stamp != "" && stamp == c.stamp {
+		return c.version, nil
+	}
+	v, err := compute()
+	if err != nil {
+		return DatasetVersion{}, err
+	}
+	v.LoadedAt = time.Now()
+	c.stamp, c.version = stamp, v
+	return v, nil
+}
+
+// invalidate forces the next get to recompute the version.
+func (c *versionCache) invalidate() {
+	c.mu.Lock()
+	c.stamp = ""
+	c.mu.Unlock()
+}
@@ -0,0 +1,200 @@
+// Package export writes the passenger dataset in downloadable file formats.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is a supported export file format.
+type Format string
+
+const (
+	CSV     Format = "csv"
+	Parquet Format = "parquet"
+	XLSX    Format = "xlsx"
+)
+
+// ParseFormat validates a format name supplied by a client.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case CSV, Parquet, XLSX:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q, valid formats are: %s, %s, %s", s, CSV, Parquet, XLSX)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case Parquet:
+		return "application/vnd.apache.parquet"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Filename returns the download name for an export of the given dataset version.
+func (f Format) Filename(version string) string {
+	if len(version) > 12 {
+		version = version[:12]
+	}
+	return fmt.Sprintf("titanic-%s.%s", version, f)
+}
+
+// header holds the column names shared by every format, matching the original Titanic CSV.
+var header = []string{"PassengerId", "Survived", "Pclass", "Name", "Sex", "Age", "SibSp", "Parch", "Ticket", "Fare", "Cabin", "Embarked"}
+
+// batchSize is the number of rows buffered between writes to the Parquet writer.
+const batchSize = 1024
+
+// Write encodes the passengers produced by seq to w. CSV is streamed row by row.
+// Parquet rows are buffered in a single row group and XLSX rows in the
+// spreadsheet, both written out only once seq is exhausted, so nothing is
+// written to w if seq fails part way through.
+func Write(w io.Writer, f Format, seq iter.Seq2[model.Passenger, error]) error {
+	switch f {
+	case CSV:
+		return writeCSV(w, seq)
+	case Parquet:
+		return writeParquet(w, seq)
+	case XLSX:
+		return writeXLSX(w, seq)
+	}
+	return fmt.Errorf("unsupported export format %q", f)
+}
+
+func writeCSV(w io.Writer, seq iter.Seq2[model.Passenger, error]) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for p, err := range seq {
+		if err != nil {
+			return err
+		}
+		record[0] = strconv.Itoa(p.PassengerID)
+		record[1] = strconv.Itoa(p.Survived)
+		record[2] = strconv.Itoa(p.Pclass)
+		record[3] = p.Name
+		record[4] = p.Sex
+		record[5] = formatOptionalFloat(p.Age)
+		record[6] = strconv.Itoa(p.SibSp)
+		record[7] = strconv.Itoa(p.Parch)
+		record[8] = p.Ticket
+		record[9] = formatOptionalFloat(p.Fare)
+		record[10] = formatOptionalString(p.Cabin)
+		record[11] = formatOptionalString(p.Embarked)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeParquet(w io.Writer, seq iter.Seq2[model.Passenger, error]) error {
+	pw := parquet.NewGenericWriter[model.ParquetPassenger](w)
+	batch := make([]model.ParquetPassenger, 0, batchSize)
+	for p, err := range seq {
+		if err != nil {
+			return err
+		}
+		batch = append(batch, model.NewParquetPassenger(p))
+		if len(batch) == batchSize {
+			if _, err := pw.Write(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if _, err := pw.Write(batch); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+func writeXLSX(w io.Writer, seq iter.Seq2[model.Passenger, error]) error {
+	const sheet = "Passengers"
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	// Keep the header row visible while scrolling. Panes must be set before any row.
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	headerRow := make([]interface{}, len(header))
+	for i, name := range header {
+		headerRow[i] = excelize.Cell{StyleID: bold, Value: name}
+	}
+	if err := sw.SetRow("A1", headerRow, excelize.RowOpts{}); err != nil {
+		return err
+	}
+
+	row := 2
+	for p, err := range seq {
+		if err != nil {
+			return err
+		}
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		values := []interface{}{
+			p.PassengerID, p.Survived, p.Pclass, p.Name, p.Sex, optionalValue(p.Age),
+			p.SibSp, p.Parch, p.Ticket, optionalValue(p.Fare), optionalValue(p.Cabin), optionalValue(p.Embarked),
+		}
+		if err := sw.SetRow(cell, values); err != nil {
+			return err
+		}
+		row++
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	_, err = f.WriteTo(w)
+	return err
+}
+
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func formatOptionalString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+// optionalValue turns a nil pointer into an empty spreadsheet cell.
+func optionalValue[T any](v *T) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func ToPtr[T any](v T) *T {
+	return &v
+}
+
+var passengers = []model.Passenger{
+	{PassengerID: 1, Survived: 0, Pclass: 3, Name: "Braund, Mr. Owen Harris", Sex: "male", Age: ToPtr(22.0),
+		SibSp: 1, Parch: 0, Ticket: "A/5 21171", Fare: ToPtr(7.25), Embarked: ToPtr("S")},
+	{PassengerID: 2, Survived: 1, Pclass: 1, Name: "Cumings, Mrs. John Bradley", Sex: "female",
+		SibSp: 1, Parch: 0, Ticket: "PC 17599", Fare: ToPtr(71.2833), Cabin: ToPtr("C85"), Embarked: ToPtr("C")},
+}
+
+func seqOf(ps []model.Passenger, err error) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		for _, p := range ps {
+			if !yield(p, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(model.Passenger{}, err)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("parquet")
+	assert.NoError(t, err)
+	assert.Equal(t, Parquet, f)
+	assert.Equal(t, "titanic-0123456789ab.parquet", f.Filename("0123456789abcdef"))
+
+	_, err = ParseFormat("json")
+	assert.Error(t, err)
+}
+
+func TestWriteCSV_RoundTripsThroughImport(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, CSV, seqOf(passengers, nil)))
+
+	records, rejected, err := data.ParseImportCSV(&buf)
+	assert.NoError(t, err)
+	assert.Empty(t, rejected)
+	assert.Len(t, records, 2)
+	assert.Equal(t, passengers[0], records[0].Passenger)
+	assert.Equal(t, passengers[1], records[1].Passenger)
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, Parquet, seqOf(passengers, nil)))
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	for _, name := range []string{"Age", "Fare", "Cabin", "Embarked"} {
+		column, ok := file.Schema().Lookup(name)
+		assert.True(t, ok, name)
+		assert.True(t, column.Node.Optional(), name)
+	}
+	column, ok := file.Schema().Lookup("PassengerId")
+	assert.True(t, ok)
+	assert.True(t, column.Node.Required())
+
+	rows, err := parquet.Read[model.ParquetPassenger](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, passengers[0], rows[0].Passenger())
+	assert.Nil(t, rows[1].Age)
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, XLSX, seqOf(passengers, nil)))
+
+	f, err := excelize.OpenReader(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	rows, err := f.GetRows("Passengers")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, header, rows[0])
+	assert.Equal(t, "Braund, Mr. Owen Harris", rows[1][3])
+	assert.Equal(t, "", rows[2][5], "a missing age must be an empty cell")
+}
+
+func TestWriteStopsOnError(t *testing.T) {
+	for _, f := range []Format{CSV, Parquet, XLSX} {
+		var buf bytes.Buffer
+		err := Write(&buf, f, seqOf(passengers, errors.New("read failed")))
+		assert.EqualError(t, err, "read failed", string(f))
+		if f != CSV {
+			assert.Zero(t, buf.Len(), "%s must not write a partial file", f)
+		}
+	}
+}
+
+func TestWriteParquetStopsOnErrorAfterBatches(t *testing.T) {
+	many := make([]model.Passenger, 3*batchSize+1)
+	for i := range many {
+		many[i] = passengers[i%len(passengers)]
+		many[i].PassengerID = i + 1
+	}
+	var buf bytes.Buffer
+	err := Write(&buf, Parquet, seqOf(many, errors.New("read failed")))
+	assert.EqualError(t, err, "read failed")
+	assert.Zero(t, buf.Len(), "rows handed to the writer are buffered until Close")
+}
@@ -0,0 +1,32 @@
+// Package filestamp detects changes to files from their size and modification
+// time, without reading their content.
+package filestamp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Of summarises the size and modification time of the given files, so that a
+// change to any of them changes the stamp. Missing files are skipped, and the
+// latest modification time is returned alongside.
+func Of(paths ...string) (string, time.Time, error) {
+	var parts []string
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return strings.Join(parts, "|"), latest, nil
+}
@@ -0,0 +1,35 @@
+package filestamp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	assert.NoError(t, os.WriteFile(a, []byte("one"), 0o644))
+
+	stamp, latest, err := Of(a, b)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stamp)
+	again, _, err := Of(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, stamp, again, "unchanged files keep their stamp")
+
+	later := latest.Add(time.Second)
+	assert.NoError(t, os.WriteFile(b, []byte("two"), 0o644))
+	assert.NoError(t, os.Chtimes(b, later, later))
+	changed, newest, err := Of(a, b)
+	assert.NoError(t, err)
+	assert.NotEqual(t, stamp, changed, "a new file changes the stamp")
+	assert.True(t, newest.Equal(later))
+
+	empty, _, err := Of(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
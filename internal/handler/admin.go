@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportSize bounds the size of an uploaded import request.
+	maxImportSize = 32 << 20
+	// maxImportMemory is how much of an upload is held in memory; the rest is
+	// spooled to a temporary file.
+	maxImportMemory = 8 << 20
+)
+
+// ImportDataset godoc
+// @Summary      Import passengers from a CSV file
+// @Description  Validates every row of a Titanic-format CSV and applies the valid ones in one transaction.
+// @Description  `replace` swaps the whole dataset and is refused if any row is invalid. `upsert` inserts or overwrites by ID. `append-only` rejects IDs that already exist.
+// @Tags         Admin
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        file formData file true "Titanic-format CSV file"
+// @Param        mode formData string false "Import mode" Enums(replace, upsert, append-only) default(upsert)
+// @Success      200  {object}  model.ImportSummary
+// @Failure      400  {object}  model.ErrorResponse
+// @Failure      401  {object}  model.ErrorResponse
+// @Failure      403  {object}  model.ErrorResponse
+// @Failure      413  {object}  model.ErrorResponse
+// @Failure      422  {object}  model.ImportSummary
+// @Failure      500  {object}  model.ErrorResponse
+// @Failure      501  {object}  model.ErrorResponse
+// @Router       /admin/import [post]
+func (h *APIHandler) ImportDataset(c *gin.Context) {
+	importer, ok := h.Repo.(data.Importer)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, model.ErrorResponse{Message: data.ErrImportNotSupported.Error()})
+		return
+	}
+
+	// The limit must be in place before anything reads the form, which parses
+	// the whole upload.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	if err := c.Request.ParseMultipartForm(maxImportMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{Message: fmt.Sprintf("The upload exceeds the limit of %d MiB", maxImportSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid multipart form: " + err.Error()})
+		return
+	}
+
+	mode, err := data.ParseImportMode(c.DefaultPostForm("mode", string(data.ImportUpsert)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "A CSV file must be uploaded in the 'file' field: " + err.Error()})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Could not read the uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	records, rejected, err := data.ParseImportCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid CSV file: " + err.Error()})
+		return
+	}
+
+	if mode == data.ImportReplace && len(rejected) > 0 {
+		// Replacing the dataset with the valid subset would silently lose passengers.
+		c.JSON(http.StatusUnprocessableEntity, model.ImportSummary{Mode: string(mode), Rejected: len(rejected), Errors: rejected})
+		return
+	}
+
+	// The dataset is in flux until the transaction commits, so take the
+	// instance out of rotation meanwhile.
+	done := h.health.Begin("import")
+	summary, err := importer.Import(c.Request.Context(), mode, records)
+	done()
+	if errors.Is(err, data.ErrImportNotSupported) {
+		// Decorators implement Importer whether or not the repository they wrap does.
+		c.JSON(http.StatusNotImplemented, model.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "import failed", "mode", string(mode), "records", len(records), "error", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Import failed, no changes were made"})
+		return
+	}
+
+	summary.Rejected += len(rejected)
+	summary.Errors = append(summary.Errors, rejected...)
+	sort.SliceStable(summary.Errors, func(i, j int) bool { return summary.Errors[i].Line < summary.Errors[j].Line })
+	c.JSON(http.StatusOK, summary)
+}
+
+const (
+	// defaultVerifyDifferences is the number of field differences listed by
+	// default, and maxVerifyDifferences the most that can be requested.
+	defaultVerifyDifferences = 100
+	maxVerifyDifferences     = 1000
+)
+
+// VerifyDataSources godoc
+// @Summary      Compare two data sources
+// @Description  Reads every passenger from two of the data sources the server has open, the main one and its fallback by default, and reports the passengers found in only one of them, duplicate IDs, and the fields that differ between the others.
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        left            query string false "First data source, the main one by default"
+// @Param        right           query string false "Second data source, the fallback by default"
+// @Param        max_differences query int    false "Maximum number of field differences listed" default(100) maximum(1000)
+// @Success      200  {object}  model.ConsistencyReport
+// @Failure      400  {object}  model.ErrorResponse
+// @Failure      401  {object}  model.ErrorResponse
+// @Failure      403  {object}  model.ErrorResponse
+// @Failure      500  {object}  model.ErrorResponse
+// @Failure      503  {object}  model.ErrorResponse
+// @Router       /admin/verify [get]
+func (h *APIHandler) VerifyDataSources(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("max_differences", strconv.Itoa(defaultVerifyDifferences)))
+	if err != nil || limit < 1 || limit > maxVerifyDifferences {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "max_differences must be between 1 and " + strconv.Itoa(maxVerifyDifferences)})
+		return
+	}
+
+	// Only sources already open are compared: opening one here could create an
+	// empty database or start a download on every request.
+	sources := h.dataSources()
+	names := make([]string, len(sources))
+	for i, b := range sources {
+		names[i] = b.Name
+	}
+	var backends []data.Backend
+	for i, name := range []string{c.Query("left"), c.Query("right")} {
+		if name == "" && i < len(names) {
+			name = names[i]
+		}
+		at := slices.Index(names, name)
+		if at < 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: fmt.Sprintf("Unknown data source %q, the server uses %s", name, strings.Join(names, ", "))})
+			return
+		}
+		if sources[at].Repo == nil {
+			c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{Message: fmt.Sprintf("Data source %q is not open", name)})
+			return
+		}
+		backends = append(backends, sources[at])
+	}
+
+	ctx := c.Request.Context()
+	report, err := data.CompareRepositories(ctx, backends[0], backends[1], limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "data source comparison failed", "left", backends[0].Name, "right", backends[1].Name, "error", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Could not read the data sources"})
+		return
+	}
+	if !report.Consistent {
+		slog.WarnContext(ctx, "data sources differ", "left", report.Left, "right", report.Right,
+			"only_in_left", len(report.OnlyInLeft), "only_in_right", len(report.OnlyInRight), "mismatched", report.Mismatched)
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conditionalGET tags successful GET and HEAD responses with an ETag and
+// Last-Modified derived from the repository's dataset version, and with
+// cacheControl, when set, as the Cache-Control header. When the client already
+// holds the current representation, a successful response is replaced by 304
+// Not Modified. The handler still runs, so that errors such as an unknown
+// passenger are reported as they would be otherwise, and never cached.
+func (h *APIHandler) conditionalGET(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		version, err := h.Repo.Version(c.Request.Context())
+		if err != nil {
+			// Serve the request uncached rather than failing it.
+			slog.WarnContext(c.Request.Context(), "could not compute dataset version", "error", err)
+			c.Next()
+			return
+		}
+
+		etag := representationETag(version.Hash, wantsNDJSON(c))
+		c.Header("Vary", "Accept")
+		w := &conditionalWriter{
+			ResponseWriter: c.Writer,
+			etag:           etag,
+			cacheControl:   cacheControl,
+			notModified:    notModified(c.Request, etag, version.ModTime),
+		}
+		if !version.ModTime.IsZero() {
+			w.lastModified = version.ModTime.UTC().Format(http.TimeFormat)
+		}
+		c.Writer = w
+		c.Next()
+		// The handler may have set a status without writing anything.
+		w.commit()
+		c.Writer = w.ResponseWriter
+	}
+}
+
+// conditionalWriter holds back the caching headers until the handler commits
+// to a status, and adds them only if that status is 2xx.
+type conditionalWriter struct {
+	gin.ResponseWriter
+	etag, lastModified, cacheControl string
+	// notModified is set when the client's validators match the current version.
+	notModified bool
+
+	committed bool
+	// discard is set once a 304 has been sent in place of the handler's response.
+	discard bool
+}
+
+// commit runs once, when the handler starts writing its response.
+func (w *conditionalWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	if status := w.ResponseWriter.Status(); status < 200 || status > 299 {
+		return
+	}
+
+	header := w.Header()
+	header.Set("ETag", w.etag)
+	if w.lastModified != "" {
+		header.Set("Last-Modified", w.lastModified)
+	}
+	if w.cacheControl != "" {
+		header.Set("Cache-Control", w.cacheControl)
+	}
+	if w.notModified {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		w.ResponseWriter.WriteHeaderNow()
+		w.discard = true
+	}
+}
+
+func (w *conditionalWriter) WriteHeader(code int) {
+	if !w.discard {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *conditionalWriter) WriteHeaderNow() {
+	w.commit()
+	if !w.discard {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *conditionalWriter) Write(b []byte) (int, error) {
+	w.commit()
+	if w.discard {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *conditionalWriter) WriteString(s string) (int, error) {
+	w.commit()
+	if w.discard {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *conditionalWriter) Flush() {
+	w.commit()
+	if !w.discard {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// representationETag builds a strong ETag from the dataset hash. The negotiated
+// format is mixed in because JSON and NDJSON are served from the same URL.
+func representationETag(datasetHash string, ndjson bool) string {
+	variant := datasetHash
+	if ndjson {
+		variant += "\x00ndjson"
+	}
+	sum := sha256.Sum256([]byte(variant))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates If-None-Match and, only when that is absent, If-Modified-Since,
+// following RFC 9110 section 13.2.2.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have one-second resolution.
+		return !modTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/export"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportDataset godoc
+// @Summary      Download the dataset
+// @Description  Downloads the current dataset as CSV, Parquet or XLSX, optionally filtered. The file name includes the dataset version.
+// @Description  Parquet files use a typed schema in which Age, Fare, Cabin and Embarked are optional columns.
+// @Tags         Export
+// @Produce      text/csv
+// @Produce      application/vnd.apache.parquet
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security     BearerAuth
+// @Param        format   query string false "File format" Enums(csv, parquet, xlsx) default(csv)
+// @Param        survived query int    false "Only passengers with this survival flag" Enums(0, 1)
+// @Param        pClass   query int    false "Only passengers in this class" Enums(1, 2, 3)
+// @Param        sex      query string false "Only passengers of this sex" Enums(male, female)
+// @Param        embarked query string false "Only passengers embarked at this port" Enums(C, Q, S)
+// @Success      200  {file}    file
+// @Failure      400  {object}  model.ErrorResponse
+// @Failure      500  {object}  model.ErrorResponse
+// @Router       /export [get]
+func (h *APIHandler) ExportDataset(c *gin.Context) {
+	format, err := export.ParseFormat(c.DefaultQuery("format", string(export.CSV)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+		return
+	}
+	filter, err := parsePassengerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	version, err := h.Repo.Version(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "could not compute dataset version", "error", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to determine the dataset version"})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="`+format.Filename(version.Hash)+`"`)
+	err = export.Write(c.Writer, format, filter.apply(h.Repo.Passengers(c.Request.Context())))
+	if err == nil {
+		return
+	}
+	slog.ErrorContext(c.Request.Context(), "could not export passengers", "format", string(format), "error", err)
+	if !c.Writer.Written() {
+		// Drop the headers describing the file, so the error is neither saved
+		// as a download nor cached in its place.
+		header := c.Writer.Header()
+		for _, name := range []string{"Content-Type", "Content-Disposition", "ETag", "Last-Modified", "Cache-Control"} {
+			header.Del(name)
+		}
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to export passengers"})
+		return
+	}
+	// Part of the file has been sent, so the status can no longer change.
+}
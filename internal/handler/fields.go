@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// passengerFieldIndex maps the lower-cased field name to its position in
+// model.PassengerFields, which lists every field the fields parameter can select.
+var passengerFieldIndex = func() map[string]int {
+	index := make(map[string]int, len(model.PassengerFields))
+	for i, f := range model.PassengerFields {
+		index[strings.ToLower(f.Name)] = i
+	}
+	return index
+}()
+
+// validFieldNames returns the selectable field names in declaration order.
+func validFieldNames() []string {
+	names := make([]string, len(model.PassengerFields))
+	for i, f := range model.PassengerFields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// fieldSet is a parsed, validated selection of passenger fields.
+type fieldSet []int
+
+// parseFieldSet resolves the requested field names case-insensitively. Each value
+// may itself be a comma-separated list. Duplicates are ignored, and an unknown
+// name produces an error listing the valid ones.
+func parseFieldSet(values []string) (fieldSet, error) {
+	var fields fieldSet
+	seen := make(map[int]bool)
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			i, ok := passengerFieldIndex[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q, valid fields are: %s", name, strings.Join(validFieldNames(), ", "))
+			}
+			if !seen[i] {
+				seen[i] = true
+				fields = append(fields, i)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// project returns a map holding only the selected fields of the passenger.
+// Optional fields that are not set are left out, as in the full encoding.
+func (fs fieldSet) project(p *model.Passenger) map[string]interface{} {
+	out := make(map[string]interface{}, len(fs))
+	for _, i := range fs {
+		f := model.PassengerFields[i]
+		if v := f.Get(p); v != nil {
+			out[f.Name] = v
+		}
+	}
+	return out
+}
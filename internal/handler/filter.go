@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// passengerFilter restricts a passenger listing by exact attribute matches.
+// A nil criterion matches every passenger.
+type passengerFilter struct {
+	survived *int
+	pclass   *int
+	sex      *string
+	embarked *string
+}
+
+// parsePassengerFilter reads the survived, pClass, sex and embarked query parameters.
+func parsePassengerFilter(c *gin.Context) (passengerFilter, error) {
+	var f passengerFilter
+	var err error
+	if f.survived, err = queryInt(c, "survived"); err != nil {
+		return f, err
+	}
+	if f.pclass, err = queryInt(c, "pClass"); err != nil {
+		return f, err
+	}
+	if sex, ok := c.GetQuery("sex"); ok {
+		sex = strings.ToLower(sex)
+		f.sex = &sex
+	}
+	if embarked, ok := c.GetQuery("embarked"); ok {
+		embarked = strings.ToUpper(embarked)
+		f.embarked = &embarked
+	}
+	return f, nil
+}
+
+func queryInt(c *gin.Context, key string) (*int, error) {
+	s, ok := c.GetQuery(key)
+	if !ok {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer, got %q", key, s)
+	}
+	return &v, nil
+}
+
+// match reports whether the passenger satisfies every criterion.
+func (f passengerFilter) match(p *model.Passenger) bool {
+	if f.survived != nil && p.Survived != *f.survived {
+		return false
+	}
+	if f.pclass != nil && p.Pclass != *f.pclass {
+		return false
+	}
+	if f.sex != nil && p.Sex != *f.sex {
+		return false
+	}
+	if f.embarked != nil && (p.Embarked == nil || *p.Embarked != *f.embarked) {
+		return false
+	}
+	return true
+}
+
+// apply wraps seq so that it only yields matching passengers.
+func (f passengerFilter) apply(seq iter.Seq2[model.Passenger, error]) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		for p, err := range seq {
+			if err == nil && !f.match(&p) {
+				continue
+			}
+			if !yield(p, err) {
+				return
+			}
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+	"github.com/dhope-nagesh/titanic-go-service/internal/cache"
 	"github.com/dhope-nagesh/titanic-go-service/internal/data"
-	"github.com/dhope-nagesh/titanic-go-service/internal/model"
-	"log"
-	"reflect"
-	"strings"
+	"github.com/dhope-nagesh/titanic-go-service/internal/health"
+	"github.com/dhope-nagesh/titanic-go-service/internal/jobs"
+	"github.com/dhope-nagesh/titanic-go-service/internal/ratelimit"
+	"log/slog"
+	"os"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -16,61 +19,150 @@ import (
 
 type APIHandler struct {
 	Repo data.PassengerRepository
+
+	cacheControl  map[string]string
+	batchGetLimit int
+	auth          auth.Authenticator
+	rateLimit     *ratelimit.Policy
+	jobs          *jobs.Manager
+	health        *health.Checker
+	dataSources   func() []data.Backend
+	cache         *cache.Repository
+	sourceName    string
+}
+
+// defaultBatchGetLimit caps the number of IDs in a batch lookup when no limit is configured.
+const defaultBatchGetLimit = 100
+
+// Option customises an APIHandler.
+type Option func(*APIHandler)
+
+// WithCacheControl sets the Cache-Control header sent by each route group,
+// keyed by group name ("passengers", "stats", "export").
+func WithCacheControl(cacheControl map[string]string) Option {
+	return func(h *APIHandler) {
+		h.cacheControl = cacheControl
+	}
 }
 
-func NewAPIHandler(repo data.PassengerRepository) *APIHandler {
+// WithBatchGetLimit sets the maximum number of IDs accepted by a single batch lookup.
+func WithBatchGetLimit(limit int) Option {
+	return func(h *APIHandler) {
+		if limit > 0 {
+			h.batchGetLimit = limit
+		}
+	}
+}
+
+// WithAuth requires every request to authenticate, and enforces the role each
+// route group needs: read for queries, write for jobs and admin for the admin API.
+// Without it the read and write routes are open and the admin API is disabled.
+func WithAuth(a auth.Authenticator) Option {
+	return func(h *APIHandler) {
+		h.auth = a
+	}
+}
+
+// WithRateLimit throttles clients according to the policy's per-group limits
+// ("passengers", "stats", "export", "jobs", "admin") and daily quota.
+func WithRateLimit(p *ratelimit.Policy) Option {
+	return func(h *APIHandler) {
+		h.rateLimit = p
+	}
+}
+
+// WithHealthCheck adds a dependency to the readiness probe. The repository is
+// always checked, under the name "data".
+func WithHealthCheck(name string, check health.CheckFunc) Option {
+	return func(h *APIHandler) {
+		h.health.Register(name, check)
+	}
+}
+
+// WithJobs enables the asynchronous job endpoints.
+func WithJobs(manager *jobs.Manager) Option {
+	return func(h *APIHandler) {
+		h.jobs = manager
+	}
+}
+
+// WithCache caches computed statistics in c until the dataset changes. c is
+// normally the caching decorator the handler's repository is built on.
+func WithCache(c *cache.Repository) Option {
+	return func(h *APIHandler) {
+		h.cache = c
+	}
+}
+
+// WithDataSources enables the admin endpoint comparing the data sources the
+// server has open, as listed by sources for each request.
+func WithDataSources(sources func() []data.Backend) Option {
+	return func(h *APIHandler) {
+		h.dataSources = sources
+	}
+}
+
+// WithSourceName names the data source the repository reads, as reported by /version.
+func WithSourceName(name string) Option {
+	return func(h *APIHandler) {
+		h.sourceName = name
+	}
+}
+
+func NewAPIHandler(repo data.PassengerRepository, opts ...Option) *APIHandler {
 	if repo == nil {
-		log.Fatal("Repository cannot be nil")
+		slog.Error("repository cannot be nil")
+		os.Exit(1)
+	}
+	h := &APIHandler{Repo: repo, batchGetLimit: defaultBatchGetLimit, health: health.NewChecker()}
+	h.health.Register("data", repo.Ping)
+	for _, opt := range opts {
+		opt(h)
 	}
-	return &APIHandler{Repo: repo}
+	return h
 }
 
 func (h *APIHandler) RegisterRoutes(router *gin.Engine) {
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Probes are open and unthrottled so that the kubelet can always reach them.
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+	router.GET("/version", h.GetVersion)
 
-	api := router.Group("/api/v1")
+	router.GET("/swagger/*any", h.require(auth.RoleRead), ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	api := router.Group("/api/v1", h.require(auth.RoleRead))
 	{
-		passengers := api.Group("/passengers")
+		passengers := api.Group("/passengers", h.limit("passengers"), h.conditionalGET(h.cacheControl["passengers"]))
 		{
 			passengers.GET("", h.GetAllPassengers)
 			passengers.GET("/:id", h.GetPassengerByID)
 			passengers.GET("/:id/attributes", h.GetPassengerAttributes)
 		}
-		stats := api.Group("/stats")
+		// Custom methods on the collection use a ":verb" suffix, e.g. POST /passengers:batchGet.
+		api.POST("/passengers:verb", h.limit("passengers"), h.passengerCollectionMethod)
+
+		stats := api.Group("/stats", h.limit("stats"), h.conditionalGET(h.cacheControl["stats"]))
 		{
 			stats.GET("/fare_histogram", h.GetFareHistogram)
 		}
-	}
-}
-
-// Helper to convert a passenger to a map and filter by attributes
-func filterPassengerAttributes(p model.Passenger, attrs []string) map[string]interface{} {
-	passengerValue := reflect.ValueOf(p)
-	passengerType := reflect.TypeOf(p)
-
-	filteredMap := make(map[string]interface{})
-
-	// Create a map of Go field names to requested attributes for easy lookup
-	requestedAttrs := make(map[string]bool)
-	for _, attr := range attrs {
-		requestedAttrs[attr] = true
-	}
+		api.GET("/export", h.limit("export"), h.conditionalGET(h.cacheControl["export"]), h.ExportDataset)
 
-	for i := 0; i < passengerType.NumField(); i++ {
-		field := passengerType.Field(i)
-		fieldName := field.Name
-
-		// Check if this Go field name was requested by the user
-		if _, ok := requestedAttrs[fieldName]; ok {
-			jsonTag := field.Tag.Get("json")
-
-			jsonFieldName := strings.Split(jsonTag, ",")[0]
+		if h.jobs != nil {
+			jobGroup := api.Group("/jobs", h.limit("jobs"))
+			{
+				jobGroup.POST("", h.require(auth.RoleWrite), h.SubmitJob)
+				jobGroup.GET("/:id", h.GetJob)
+				jobGroup.DELETE("/:id", h.require(auth.RoleWrite), h.CancelJob)
+				jobGroup.GET("/:id/result", h.GetJobResult)
+			}
+		}
 
-			if jsonFieldName != "" && jsonFieldName != "-" {
-				fieldValue := passengerValue.Field(i)
-				filteredMap[jsonFieldName] = fieldValue.Interface()
+		admin := api.Group("/admin", h.require(auth.RoleAdmin), h.limit("admin"))
+		{
+			admin.POST("/import", h.ImportDataset)
+			if h.dataSources != nil {
+				admin.GET("/verify", h.VerifyDataSources)
 			}
 		}
 	}
-	return filteredMap
 }
@@ -1,10 +1,30 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	dataset "github.com/dhope-nagesh/titanic-go-service/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/jobs"
 	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/dhope-nagesh/titanic-go-service/internal/ratelimit"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,8 +42,9 @@ func TestFilterPassengerAttributes_AllAttributes(t *testing.T) {
 		Survived:    1,
 	}
 
-	attrs := []string{"PassengerID", "Name", "Age", "Sex", "Pclass", "Survived"}
-	result := filterPassengerAttributes(passenger, attrs)
+	fields, err := parseFieldSet([]string{"passengerId", "name", "age", "sex", "pClass", "survived"})
+	assert.NoError(t, err)
+	result := fields.project(&passenger)
 
 	assert.Equalf(t, 1, result["passengerId"], fmt.Sprintf("Expected %v, got %v", 1, result))
 	assert.Equal(t, "John Doe", result["name"])
@@ -43,8 +64,9 @@ func TestFilterPassengerAttributes_SpecificAttributes(t *testing.T) {
 		Survived:    1,
 	}
 
-	attrs := []string{"Name", "Age"}
-	result := filterPassengerAttributes(passenger, attrs)
+	fields, err := parseFieldSet([]string{"name,age"})
+	assert.NoError(t, err)
+	result := fields.project(&passenger)
 
 	assert.Equal(t, "John Doe", result["name"])
 	assert.Equal(t, ToPtr(float64(30)), result["age"])
@@ -52,6 +74,18 @@ func TestFilterPassengerAttributes_SpecificAttributes(t *testing.T) {
 	assert.NotContains(t, result, "sex")
 }
 
+func TestFilterPassengerAttributes_CaseInsensitive(t *testing.T) {
+	passenger := model.Passenger{PassengerID: 7, Pclass: 2}
+
+	fields, err := parseFieldSet([]string{"PassengerID", "PCLASS", "pclass"})
+	assert.NoError(t, err)
+	result := fields.project(&passenger)
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, 7, result["passengerId"])
+	assert.Equal(t, 2, result["pClass"])
+}
+
 func TestFilterPassengerAttributes_NoAttributes(t *testing.T) {
 	passenger := model.Passenger{
 		PassengerID: 1,
@@ -62,24 +96,732 @@ func TestFilterPassengerAttributes_NoAttributes(t *testing.T) {
 		Survived:    1,
 	}
 
-	attrs := []string{}
-	result := filterPassengerAttributes(passenger, attrs)
+	fields, err := parseFieldSet([]string{})
+	assert.NoError(t, err)
+	result := fields.project(&passenger)
 
 	assert.Empty(t, result)
 }
 
 func TestFilterPassengerAttributes_InvalidAttributes(t *testing.T) {
-	passenger := model.Passenger{
-		PassengerID: 1,
-		Name:        "John Doe",
-		Age:         ToPtr(float64(30)),
-		Sex:         "male",
-		Pclass:      1,
-		Survived:    0,
+	_, err := parseFieldSet([]string{"InvalidField"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "InvalidField")
+	assert.Contains(t, err.Error(), "passengerId")
+}
+
+// TestPassengerFieldsMatchModel guards the field table against drifting from the JSON tags of model.Passenger.
+func TestPassengerFieldsMatchModel(t *testing.T) {
+	typ := reflect.TypeOf(model.Passenger{})
+	assert.Equal(t, typ.NumField(), len(model.PassengerFields))
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		assert.Equal(t, name, model.PassengerFields[i].Name)
 	}
 
-	attrs := []string{"InvalidField"}
-	result := filterPassengerAttributes(passenger, attrs)
+	p := model.Passenger{PassengerID: 3, Name: "Jane", Age: ToPtr(22.0), Cabin: ToPtr("C85")}
+	full, err := json.Marshal(p)
+	assert.NoError(t, err)
+	var want map[string]interface{}
+	assert.NoError(t, json.Unmarshal(full, &want))
 
-	assert.Empty(t, result)
+	fields, err := parseFieldSet(validFieldNames())
+	assert.NoError(t, err)
+	projected, err := json.Marshal(fields.project(&p))
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(projected, &got))
+	assert.Equal(t, want, got, "unset optional fields are left out of both")
+}
+
+type stubRepository struct {
+	passengers []model.Passenger
+	err        error
+	version    data.DatasetVersion
+}
+
+func (s *stubRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	return s.passengers, nil
+}
+
+func (s *stubRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for i := range s.passengers {
+		if s.passengers[i].PassengerID == id {
+			return &s.passengers[i], nil
+		}
+	}
+	return nil, data.ErrPassengerNotFound
+}
+
+func (s *stubRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	var found []model.Passenger
+	for _, id := range ids {
+		if p, err := s.GetPassengerByID(ctx, id); err == nil {
+			found = append(found, *p)
+		}
+	}
+	return found, nil
+}
+
+func (s *stubRepository) GetFares(ctx context.Context) ([]float64, error) {
+	return nil, nil
+}
+
+func (s *stubRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		for _, p := range s.passengers {
+			if !yield(p, nil) {
+				return
+			}
+		}
+		if s.err != nil {
+			yield(model.Passenger{}, s.err)
+		}
+	}
+}
+
+func (s *stubRepository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	return s.version, nil
+}
+
+func (s *stubRepository) Ping(ctx context.Context) error {
+	if len(s.passengers) == 0 {
+		return data.ErrEmptyDataset
+	}
+	return nil
+}
+
+func (s *stubRepository) Close() error {
+	return nil
+}
+
+func newTestRouter() *gin.Engine {
+	return newTestRouterWithRepo(&stubRepository{passengers: []model.Passenger{
+		{PassengerID: 1, Name: "John Doe", Pclass: 3, Age: ToPtr(22.0)},
+		{PassengerID: 2, Name: "Jane Doe", Pclass: 1},
+	}})
+}
+
+func newTestRouterWithRepo(repo data.PassengerRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(repo).RegisterRoutes(router)
+	return router
+}
+
+func TestGetAllPassengers_Fields(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers?fields=passengerId,NAME", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body, 2)
+	assert.Equal(t, map[string]interface{}{"passengerId": 1.0, "name": "John Doe"}, body[0])
+}
+
+func TestGetPassengerByID_Fields(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers/1?fields=pclass&fields=age", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"pClass":3,"age":22}`, w.Body.String())
+}
+
+func TestGetPassengerByID_Errors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		repo data.PassengerRepository
+		want int
+	}{
+		"not found":        {&stubRepository{}, http.StatusNotFound},
+		"repository error": {&stubRepository{err: errors.New("disk on fire")}, http.StatusInternalServerError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestRouterWithRepo(tc.repo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/passengers/1", nil))
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+func TestGetAllPassengers_UnknownField(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers?fields=name,colour", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body model.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Contains(t, body.Message, `"colour"`)
+	assert.Contains(t, body.Message, "embarked")
+}
+
+func TestGetAllPassengers_StreamsJSONArray(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	var body []model.Passenger
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body, 2)
+	assert.Equal(t, "Jane Doe", body[1].Name)
+}
+
+func TestGetAllPassengers_EmptyDataset(t *testing.T) {
+	router := newTestRouterWithRepo(&stubRepository{})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestGetAllPassengers_NDJSON(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers?fields=passengerId", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"passengerId\":1}\n{\"passengerId\":2}\n", w.Body.String())
+}
+
+func TestGetAllPassengers_ErrorBeforeFirstRecord(t *testing.T) {
+	router := newTestRouterWithRepo(&stubRepository{err: errors.New("disk on fire")})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestGetAllPassengers_ErrorMidStream(t *testing.T) {
+	router := newTestRouterWithRepo(&stubRepository{
+		passengers: []model.Passenger{{PassengerID: 1}},
+		err:        errors.New("disk on fire"),
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body []model.Passenger
+	assert.Error(t, json.Unmarshal(w.Body.Bytes(), &body), "a truncated stream must not parse as a complete array")
+}
+
+func TestConditionalGET(t *testing.T) {
+	modTime := time.Date(2024, 4, 15, 2, 20, 0, 0, time.UTC)
+	repo := &stubRepository{
+		passengers: []model.Passenger{{PassengerID: 1}},
+		version:    data.DatasetVersion{Hash: "abc123", ModTime: modTime, Rows: 1},
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(repo, WithCacheControl(map[string]string{"passengers": "public, max-age=60"})).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Mon, 15 Apr 2024 02:20:00 GMT", w.Header().Get("Last-Modified"))
+
+	t.Run("matching If-None-Match", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+		req.Header.Set("If-None-Match", `"other", `+etag)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("stale If-None-Match", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+		req.Header.Set("If-None-Match", `"other"`)
+		req.Header.Set("If-Modified-Since", "Tue, 16 Apr 2024 00:00:00 GMT")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "If-Modified-Since must be ignored when If-None-Match is present")
+	})
+
+	t.Run("NDJSON has its own ETag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers?format=ndjson", nil)
+		req.Header.Set("If-None-Match", etag)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers/1", nil)
+		req.Header.Set("If-Modified-Since", "Mon, 15 Apr 2024 02:20:00 GMT")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/passengers/1", nil)
+		req.Header.Set("If-Modified-Since", "Sun, 14 Apr 2024 00:00:00 GMT")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("missing passenger", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers/999", nil)
+		req.Header.Set("If-None-Match", etag)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, "a matching ETag must not hide an error")
+		for _, name := range []string{"ETag", "Last-Modified", "Cache-Control"} {
+			assert.Empty(t, w.Header().Get(name), name)
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/passengers?fields=bogus", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("new dataset version", func(t *testing.T) {
+		repo.version.Hash = "def456"
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+		req.Header.Set("If-None-Match", etag)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestExportDataset_ErrorBeforeFirstRecord(t *testing.T) {
+	repo := &stubRepository{
+		err:     errors.New("disk on fire"),
+		version: data.DatasetVersion{Hash: "abc123", ModTime: time.Date(2024, 4, 15, 2, 20, 0, 0, time.UTC)},
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(repo, WithCacheControl(map[string]string{"export": "private, max-age=300"})).RegisterRoutes(router)
+
+	for _, format := range []string{"csv", "parquet", "xlsx"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/export?format="+format, nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, format)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), format)
+		for _, name := range []string{"Content-Disposition", "ETag", "Last-Modified", "Cache-Control"} {
+			assert.Empty(t, w.Header().Get(name), "%s: %s", format, name)
+		}
+	}
+}
+
+func TestBatchGetPassengers(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/passengers:batchGet", strings.NewReader(`{"ids":[2,99,1,2]}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body model.BatchGetResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Passengers, 2)
+	assert.Equal(t, 2, body.Passengers[0].PassengerID)
+	assert.Equal(t, 1, body.Passengers[1].PassengerID)
+	assert.Equal(t, []int{99}, body.Missing)
+}
+
+func TestBatchGetPassengers_Fields(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/passengers:batchGet?fields=name", strings.NewReader(`{"ids":[1]}`))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"passengers":[{"name":"John Doe"}],"missing":[]}`, w.Body.String())
+}
+
+func TestBatchGetPassengers_Invalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(&stubRepository{}, WithBatchGetLimit(2)).RegisterRoutes(router)
+
+	for name, tc := range map[string]struct {
+		path, body string
+		code       int
+	}{
+		"malformed body": {"/api/v1/passengers:batchGet", `{"ids":"1"}`, http.StatusBadRequest},
+		"no ids":         {"/api/v1/passengers:batchGet", `{"ids":[]}`, http.StatusBadRequest},
+		"over limit":     {"/api/v1/passengers:batchGet", `{"ids":[1,2,3]}`, http.StatusBadRequest},
+		"unknown method": {"/api/v1/passengers:batchDelete", `{"ids":[1]}`, http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
+type importingRepository struct {
+	stubRepository
+	mode    data.ImportMode
+	records []data.ImportRecord
+}
+
+func (r *importingRepository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	r.mode, r.records = mode, records
+	return model.ImportSummary{Mode: string(mode), Inserted: len(records), Errors: []model.ImportError{}}, nil
+}
+
+// testAuthenticator accepts "s3cret" as an admin key and "r3ad" as a read-only key.
+var testAuthenticator = auth.APIKeyAuthenticator{Store: auth.NewStaticKeyStore([]auth.APIKey{
+	{Name: "admin", Hash: auth.HashKey("s3cret"), Role: auth.RoleAdmin},
+	{Name: "reader", Hash: auth.HashKey("r3ad"), Role: auth.RoleRead},
+})}
+
+func newImportRequest(t *testing.T, mode, content string) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if mode != "" {
+		assert.NoError(t, mw.WriteField("mode", mode))
+	}
+	fw, err := mw.CreateFormFile("file", "titanic.csv")
+	assert.NoError(t, err)
+	fw.Write([]byte(content))
+	assert.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/import", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer s3cret")
+	return req
+}
+
+func TestImportDataset(t *testing.T) {
+	content := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n" +
+		"1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n" +
+		"2,1,9,Bad Class,female,25,1,1,54321,200.0,,C\n"
+	repo := &importingRepository{}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(repo, WithAuth(testAuthenticator)).RegisterRoutes(router)
+
+	t.Run("upsert by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newImportRequest(t, "", content))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, data.ImportUpsert, repo.mode)
+		assert.Len(t, repo.records, 1)
+		var summary model.ImportSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, 1, summary.Inserted)
+		assert.Equal(t, 1, summary.Rejected)
+		assert.Equal(t, 3, summary.Errors[0].Line)
+	})
+
+	t.Run("replace refuses invalid rows", func(t *testing.T) {
+		repo.records = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newImportRequest(t, "replace", content))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Nil(t, repo.records, "nothing must be imported")
+	})
+
+	t.Run("oversized upload", func(t *testing.T) {
+		repo.records = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newImportRequest(t, "", content+strings.Repeat("x", maxImportSize)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Nil(t, repo.records, "nothing must be imported")
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newImportRequest(t, "merge", content))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		req := newImportRequest(t, "", content)
+		req.Header.Set("Authorization", "Bearer guess")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("read-only key", func(t *testing.T) {
+		req := newImportRequest(t, "", content)
+		req.Header.Set("Authorization", "Bearer r3ad")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestImportDataset_Unsupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(&stubRepository{}, WithAuth(testAuthenticator)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newImportRequest(t, "", "PassengerId\n"))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestImportDataset_Disabled(t *testing.T) {
+	router := newTestRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newImportRequest(t, "", "PassengerId\n"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(&stubRepository{}, WithAuth(testAuthenticator)).RegisterRoutes(router)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no key", "", "", http.StatusUnauthorized},
+		{"unknown key", "X-API-Key", "guess", http.StatusUnauthorized},
+		{"bearer key", "Authorization", "Bearer r3ad", http.StatusOK},
+		{"API key header", "X-API-Key", "r3ad", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	policy := ratelimit.NewPolicy(map[string]ratelimit.Limit{"passengers": {Rate: 0.001, Burst: 1}}, nil)
+	NewAPIHandler(&stubRepository{}, WithAuth(testAuthenticator), WithRateLimit(policy)).RegisterRoutes(router)
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/passengers", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusOK, get("r3ad").Code)
+	w := get("r3ad")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, get("s3cret").Code, "each key has its own bucket")
+
+	// Other route groups are not limited.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/fare_histogram", nil)
+	req.Header.Set("X-API-Key", "r3ad")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	repo := &stubRepository{passengers: []model.Passenger{{PassengerID: 1}}}
+	h := NewAPIHandler(repo, WithAuth(testAuthenticator))
+	h.RegisterRoutes(router)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/healthz")
+	assert.Equal(t, http.StatusOK, w.Code, "probes need no credentials")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, http.StatusOK, get("/readyz").Code)
+
+	w = get("/readyz?verbose")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report model.HealthReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "data", report.Checks[0].Name)
+
+	done := h.health.Begin("import")
+	w = get("/readyz?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"maintenance":["import"]`)
+	assert.Equal(t, http.StatusOK, get("/healthz").Code, "maintenance does not affect liveness")
+	done()
+
+	repo.passengers = nil
+	w = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "unavailable", w.Body.String())
+}
+
+func TestVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	repo := &stubRepository{version: data.DatasetVersion{Hash: "abc123", Rows: 2, LoadedAt: time.Now()}}
+	NewAPIHandler(repo, WithAuth(testAuthenticator), WithSourceName("embedded")).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "the version needs no credentials")
+	var info model.VersionInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.NotEmpty(t, info.Version)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, dataset.Checksum(), info.EmbeddedChecksum)
+	if assert.NotNil(t, info.Dataset) {
+		assert.Equal(t, "embedded", info.Dataset.Source)
+		assert.Equal(t, "abc123", info.Dataset.Hash)
+		assert.Equal(t, 2, info.Dataset.Rows)
+		assert.Nil(t, info.Dataset.ModifiedAt, "an unknown modification time is omitted")
+	}
+}
+
+func TestJobEndpoints(t *testing.T) {
+	store, err := jobs.OpenStore(filepath.Join(t.TempDir(), "jobs.db"))
+	assert.NoError(t, err)
+	defer store.Close()
+	manager, err := jobs.NewManager(store, jobs.Config{ResultDir: t.TempDir()})
+	assert.NoError(t, err)
+	manager.Register("echo", func(ctx context.Context, task *jobs.Task) (jobs.Result, error) {
+		return jobs.Result{Value: task.Params}, nil
+	}, func(params json.RawMessage) error {
+		if strings.Contains(string(params), "bad") {
+			return errors.New("bad params")
+		}
+		return nil
+	})
+	assert.NoError(t, manager.Start())
+	defer manager.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(&stubRepository{}, WithJobs(manager)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/jobs", strings.NewReader(`{"kind":"echo","params":{"n":1}}`)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var job model.Job
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/api/v1/jobs/"+job.ID, w.Header().Get("Location"))
+
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+job.ID, nil))
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job.Status == model.JobSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.JSONEq(t, `{"n":1}`, string(job.Result))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+job.ID+"/result", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"n":1}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/jobs", strings.NewReader(`{"kind":"train"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/jobs", strings.NewReader(`{"kind":"echo","params":{"bad":1}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid params: bad params")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/nope", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestVerifyDataSources(t *testing.T) {
+	header := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"
+	dir := t.TempDir()
+	files := map[string]string{
+		"csv":    header + "1,1,1,John Doe,male,30,0,0,12345,100.0,C123,S\n2,0,3,Jane Roe,female,25,1,1,54321,200.0,,C\n",
+		"sqlite": header + "1,1,1,John Doe,male,30,0,0,12345,100.5,C123,S\n",
+	}
+	var sources []data.Backend
+	for _, name := range []string{"csv", "sqlite"} {
+		path := filepath.Join(dir, name+".csv")
+		assert.NoError(t, os.WriteFile(path, []byte(files[name]), 0o644))
+		repo, err := data.NewCSVRepository(path)
+		assert.NoError(t, err)
+		sources = append(sources, data.Backend{Name: name, Repo: repo})
+	}
+	sources = append(sources, data.Backend{Name: "postgres"})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAPIHandler(&stubRepository{}, WithAuth(testAuthenticator), WithDataSources(func() []data.Backend { return sources })).RegisterRoutes(router)
+
+	get := func(target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/admin/verify", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report model.ConsistencyReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(t, report.Consistent)
+	assert.Equal(t, "csv", report.Left)
+	assert.Equal(t, []int{2}, report.OnlyInLeft)
+	assert.Equal(t, []model.FieldDifference{{PassengerID: 1, Field: "fare", Left: "100", Right: "100.5"}}, report.Differences)
+
+	w = get("/api/v1/admin/verify?left=csv&right=csv", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"consistent":true`)
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/admin/verify?right=oracle", "s3cret").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/api/v1/admin/verify?right=postgres", "s3cret").Code, "a source that is not open")
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/admin/verify?max_differences=0", "s3cret").Code)
+	assert.Equal(t, http.StatusForbidden, get("/api/v1/admin/verify", "r3ad").Code)
 }
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz is the liveness probe. It succeeds whenever the process can serve
+// requests, so a broken dependency never gets the pod restarted.
+func (h *APIHandler) Healthz(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+// Readyz is the readiness probe. It checks every dependency and answers 503
+// when one fails or while maintenance such as an import is running. With
+// ?verbose it returns a model.HealthReport with each check's result and latency.
+func (h *APIHandler) Readyz(c *gin.Context) {
+	report := h.health.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != model.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	if _, verbose := c.GetQuery("verbose"); verbose {
+		c.JSON(status, report)
+		return
+	}
+	c.String(status, report.Status)
+}
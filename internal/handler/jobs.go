@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/jobs"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitJob godoc
+// @Summary      Start an asynchronous job
+// @Description  Queues a long-running analysis and returns its ID immediately. Poll the job to follow its progress.
+// @Description  Kinds: `bootstrap` (params: statistic survival_rate|mean_fare|mean_age, resamples, confidence, seed), `cross_validation` (params: folds, seed) and `export` (params: format csv|parquet|xlsx).
+// @Description  Unknown kinds and invalid params are refused with 400 before anything is queued.
+// @Tags         Jobs
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body model.JobRequest true "Job kind and parameters"
+// @Success      202  {object}  model.Job
+// @Failure      400  {object}  model.ErrorResponse
+// @Failure      503  {object}  model.ErrorResponse
+// @Router       /jobs [post]
+func (h *APIHandler) SubmitJob(c *gin.Context) {
+	var req model.JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), req.Kind, req.Params)
+	switch {
+	case errors.Is(err, jobs.ErrUnknownKind), errors.Is(err, jobs.ErrInvalidParams):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+		return
+	case errors.Is(err, jobs.ErrQueueFull):
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{Message: err.Error()})
+		return
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "could not submit job", "kind", req.Kind, "error", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to submit job"})
+		return
+	}
+
+	c.Header("Location", c.FullPath()+"/"+job.ID)
+	c.JSON(http.StatusAccepted, h.presentJob(job))
+}
+
+// GetJob godoc
+// @Summary      Get a job
+// @Description  Reports the status and progress of a job, and its result or a download link once it has succeeded.
+// @Tags         Jobs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  model.Job
+// @Failure      404  {object}  model.ErrorResponse
+// @Router       /jobs/{id} [get]
+func (h *APIHandler) GetJob(c *gin.Context) {
+	job, err := h.jobs.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.presentJob(job))
+}
+
+// CancelJob godoc
+// @Summary      Cancel a job
+// @Description  Stops a queued or running job. Finished jobs are returned unchanged.
+// @Tags         Jobs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  model.Job
+// @Failure      404  {object}  model.ErrorResponse
+// @Router       /jobs/{id} [delete]
+func (h *APIHandler) CancelJob(c *gin.Context) {
+	job, err := h.jobs.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.presentJob(job))
+}
+
+// GetJobResult godoc
+// @Summary      Download a job result
+// @Description  Downloads the file produced by a succeeded job, or returns its JSON result.
+// @Tags         Jobs
+// @Produce      json
+// @Produce      octet-stream
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {file}    file
+// @Failure      404  {object}  model.ErrorResponse
+// @Failure      409  {object}  model.ErrorResponse
+// @Router       /jobs/{id}/result [get]
+func (h *APIHandler) GetJobResult(c *gin.Context) {
+	job, err := h.jobs.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.jobError(c, err)
+		return
+	}
+	if job.Status != model.JobSucceeded {
+		c.JSON(http.StatusConflict, model.ErrorResponse{Message: "The job has not succeeded, its status is " + string(job.Status)})
+		return
+	}
+	if job.File == nil {
+		c.Data(http.StatusOK, mimeJSON, job.Result)
+		return
+	}
+	c.Header("Content-Type", job.File.ContentType)
+	c.FileAttachment(job.File.Path, job.File.Name)
+}
+
+// presentJob adds the download link of a job's result file.
+func (h *APIHandler) presentJob(job model.Job) model.Job {
+	if job.Status == model.JobSucceeded && job.File != nil {
+		job.ResultURL = "/api/v1/jobs/" + job.ID + "/result"
+	}
+	return job
+}
+
+func (h *APIHandler) jobError(c *gin.Context, err error) {
+	if errors.Is(err, jobs.ErrNotFound) {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Message: err.Error()})
+		return
+	}
+	slog.ErrorContext(c.Request.Context(), "could not load job", "job_id", c.Param("id"), "error", err)
+	c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to load job"})
+}
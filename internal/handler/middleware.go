@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// require returns the middleware enforcing role on a route group. When
+// authentication is not configured only the admin role is refused, so a
+// deployment without keys keeps its read and write API but no admin API.
+func (h *APIHandler) require(role auth.Role) gin.HandlerFunc {
+	if h.auth != nil {
+		return auth.Require(h.auth, role)
+	}
+	if role == auth.RoleAdmin {
+		return func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{Message: "The admin API is disabled"})
+		}
+	}
+	return func(c *gin.Context) { c.Next() }
+}
+
+// limit returns the rate limiting middleware of a route group, or a no-op when
+// rate limiting is not configured.
+func (h *APIHandler) limit(group string) gin.HandlerFunc {
+	if h.rateLimit == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return h.rateLimit.Middleware(group)
+}
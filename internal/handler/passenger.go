@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
 	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"log/slog"
 	"net/http"
 	"strconv"
 
@@ -10,30 +14,38 @@ import (
 
 // GetAllPassengers godoc
 // @Summary      Get all passengers
-// @Description  Returns a list of all passengers
+// @Description  Returns a list of all passengers, optionally limited to the requested fields.
+// @Description  The list is streamed as it is read. Send `Accept: application/x-ndjson` or `format=ndjson` for newline-delimited JSON.
 // @Tags         Passengers
 // @Produce      json
+// @Produce      application/x-ndjson
+// @Security     BearerAuth
+// @Param        fields query []string false "Fields to include, e.g. passengerId,name" collectionFormat(csv)
+// @Param        format query string false "Response format" Enums(json, ndjson)
 // @Success      200  {array}   model.Passenger
+// @Failure      400  {object}  model.ErrorResponse
 // @Failure      500  {object}  model.ErrorResponse
 // @Router       /passengers [get]
 func (h *APIHandler) GetAllPassengers(c *gin.Context) {
-	passengers, err := h.Repo.GetAllPassengers()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to retrieve passengers"})
+	fields, ok := queryFieldSet(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, passengers)
+	streamPassengers(c, h.Repo.Passengers(c.Request.Context()), fields, wantsNDJSON(c))
 }
 
 // GetPassengerByID godoc
 // @Summary      Get a passenger by ID
-// @Description  Returns all data for a single passenger
+// @Description  Returns all data for a single passenger, optionally limited to the requested fields
 // @Tags         Passengers
 // @Produce      json
+// @Security     BearerAuth
 // @Param        id   path      int  true  "Passenger ID"
+// @Param        fields query []string false "Fields to include, e.g. passengerId,name" collectionFormat(csv)
 // @Success      200  {object}  model.Passenger
 // @Failure      400  {object}  model.ErrorResponse
 // @Failure      404  {object}  model.ErrorResponse
+// @Failure      500  {object}  model.ErrorResponse
 // @Router       /passengers/{id} [get]
 func (h *APIHandler) GetPassengerByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -41,25 +53,35 @@ func (h *APIHandler) GetPassengerByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid passenger ID format"})
 		return
 	}
+	fields, ok := queryFieldSet(c)
+	if !ok {
+		return
+	}
 
-	passenger, err := h.Repo.GetPassengerByID(id)
+	passenger, err := h.Repo.GetPassengerByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{Message: err.Error()})
+		h.passengerError(c, id, err)
 		return
 	}
-	c.JSON(http.StatusOK, passenger)
+	if fields == nil {
+		c.JSON(http.StatusOK, passenger)
+		return
+	}
+	c.JSON(http.StatusOK, fields.project(passenger))
 }
 
 // GetPassengerAttributes godoc
 // @Summary      Get specific attributes for a passenger
-// @Description  Returns only requested attributes for a passenger
+// @Description  Returns only requested attributes for a passenger. Attribute names are matched case-insensitively.
 // @Tags         Passengers
 // @Produce      json
+// @Security     BearerAuth
 // @Param        id   path      int  true  "Passenger ID"
-// @Param        attributes query []string true "List of attributes" collectionFormat(multi)
+// @Param        attributes query []string true "List of attributes, e.g. name or pClass" collectionFormat(multi)
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  model.ErrorResponse
 // @Failure      404  {object}  model.ErrorResponse
+// @Failure      500  {object}  model.ErrorResponse
 // @Router       /passengers/{id}/attributes [get]
 func (h *APIHandler) GetPassengerAttributes(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -73,13 +95,130 @@ func (h *APIHandler) GetPassengerAttributes(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "You must provide at least one attribute."})
 		return
 	}
+	fields, err := parseFieldSet(attributes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+		return
+	}
 
-	passenger, err := h.Repo.GetPassengerByID(id)
+	passenger, err := h.Repo.GetPassengerByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{Message: err.Error()})
+		h.passengerError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, fields.project(passenger))
+}
+
+// BatchGetPassengers godoc
+// @Summary      Get many passengers by ID
+// @Description  Looks up several passengers in one call. Found passengers are returned in request order and unknown IDs are listed in missing.
+// @Tags         Passengers
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body model.BatchGetRequest true "Passenger IDs to look up"
+// @Param        fields query []string false "Fields to include, e.g. passengerId,name" collectionFormat(csv)
+// @Success      200  {object}  model.BatchGetResponse
+// @Failure      400  {object}  model.ErrorResponse
+// @Failure      500  {object}  model.ErrorResponse
+// @Router       /passengers:batchGet [post]
+func (h *APIHandler) BatchGetPassengers(c *gin.Context) {
+	var req model.BatchGetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "You must provide at least one ID."})
+		return
+	}
+	if len(req.IDs) > h.batchGetLimit {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Message: fmt.Sprintf("Too many IDs: got %d, the limit is %d", len(req.IDs), h.batchGetLimit),
+		})
+		return
+	}
+	fields, ok := queryFieldSet(c)
+	if !ok {
 		return
 	}
 
-	filteredData := filterPassengerAttributes(*passenger, attributes)
-	c.JSON(http.StatusOK, filteredData)
+	// Look each ID up once, but answer in the order the client asked.
+	ids := make([]int, 0, len(req.IDs))
+	seen := make(map[int]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	found, err := h.Repo.GetPassengersByIDs(c.Request.Context(), ids)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "could not get passengers", "ids", len(ids), "error", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to retrieve passengers"})
+		return
+	}
+	byID := make(map[int]*model.Passenger, len(found))
+	for i := range found {
+		byID[found[i].PassengerID] = &found[i]
+	}
+
+	resp := model.BatchGetResponse{Passengers: make([]model.Passenger, 0, len(found)), Missing: []int{}}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			resp.Passengers = append(resp.Passengers, *p)
+		} else {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+
+	if fields == nil {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	projected := make([]map[string]interface{}, len(resp.Passengers))
+	for i := range resp.Passengers {
+		projected[i] = fields.project(&resp.Passengers[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"passengers": projected, "missing": resp.Missing})
+}
+
+// passengerCollectionMethod dispatches custom methods on the passengers collection.
+func (h *APIHandler) passengerCollectionMethod(c *gin.Context) {
+	switch c.Param("verb") {
+	case ":batchGet":
+		h.BatchGetPassengers(c)
+	default:
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Message: "Unknown method"})
+	}
+}
+
+// queryFieldSet parses the optional fields query parameter. A nil set means no
+// projection was requested. On invalid input it writes a 400 response and returns false.
+func queryFieldSet(c *gin.Context) (fieldSet, bool) {
+	values := c.QueryArray("fields")
+	if len(values) == 0 {
+		return nil, true
+	}
+	fields, err := parseFieldSet(values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+		return nil, false
+	}
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "You must provide at least one field."})
+		return nil, false
+	}
+	return fields, true
+}
+
+// passengerError responds to a failed single passenger lookup.
+func (h *APIHandler) passengerError(c *gin.Context, id int, err error) {
+	if errors.Is(err, data.ErrPassengerNotFound) {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Message: err.Error()})
+		return
+	}
+	slog.ErrorContext(c.Request.Context(), "could not get passenger", "passenger_id", id, "error", err)
+	c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to retrieve passenger"})
 }
@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"context"
 	"fmt"
+	"github.com/dhope-nagesh/titanic-go-service/internal/cache"
 	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/dhope-nagesh/titanic-go-service/internal/tracing"
+	"log/slog"
 	"net/http"
 	"sort"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gonum.org/v1/gonum/stat"
 )
 
@@ -15,20 +21,40 @@ import (
 // @Description  Returns data for a bar chart of fare prices in percentiles
 // @Tags         Statistics
 // @Produce      json
+// @Security     BearerAuth
 // @Success      200  {object}  model.FareHistogram
 // @Failure      500  {object}  model.ErrorResponse
 // @Router       /stats/fare_histogram [get]
 func (h *APIHandler) GetFareHistogram(c *gin.Context) {
-	fares, err := h.Repo.GetFares()
+	ctx := c.Request.Context()
+	var histogram model.FareHistogram
+	var err error
+	if h.cache != nil {
+		// The histogram only changes with the dataset, so it is computed once per version.
+		histogram, err = cache.Compute(ctx, h.cache, "fare_histogram", h.fareHistogram)
+	} else {
+		histogram, err = h.fareHistogram(ctx)
+	}
 	if err != nil {
+		slog.ErrorContext(ctx, "could not get fares", "error", err)
 		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to retrieve fares"})
 		return
 	}
+	c.JSON(http.StatusOK, histogram)
+}
+
+// fareHistogram counts the fares falling between consecutive deciles.
+func (h *APIHandler) fareHistogram(ctx context.Context) (model.FareHistogram, error) {
+	fares, err := h.Repo.GetFares(ctx)
+	if err != nil {
+		return model.FareHistogram{}, err
+	}
 	if len(fares) == 0 {
-		c.JSON(http.StatusOK, model.FareHistogram{Percentiles: []string{}, Counts: []int{}})
-		return
+		return model.FareHistogram{Percentiles: []string{}, Counts: []int{}}, nil
 	}
 
+	_, span := tracing.Tracer().Start(ctx, "stats.fare_histogram", trace.WithAttributes(attribute.Int("titanic.rows", len(fares))))
+
 	// The data must be sorted to calculate quantiles.
 	sort.Float64s(fares)
 
@@ -72,9 +98,10 @@ func (h *APIHandler) GetFareHistogram(c *gin.Context) {
 		}
 		counts[i] = count
 	}
+	span.End()
 
-	c.JSON(http.StatusOK, model.FareHistogram{
+	return model.FareHistogram{
 		Percentiles: labels,
 		Counts:      counts,
-	})
+	}, nil
 }
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"iter"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mimeJSON   = "application/json; charset=utf-8"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON, either
+// with ?format=ndjson or through the Accept header.
+func wantsNDJSON(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return strings.EqualFold(format, "ndjson")
+	}
+	return strings.Contains(c.GetHeader("Accept"), mimeNDJSON)
+}
+
+// streamPassengers writes passengers to the response as they are produced, either
+// as a JSON array or as NDJSON, so memory use does not grow with the dataset.
+//
+// An error before the first record becomes a 500 response. Once the body has
+// started the status can no longer change, so a later error is logged and the
+// stream is cut short, which leaves a JSON array visibly unterminated.
+func streamPassengers(c *gin.Context, seq iter.Seq2[model.Passenger, error], fields fieldSet, ndjson bool) {
+	contentType := mimeJSON
+	if ndjson {
+		contentType = mimeNDJSON
+	}
+
+	enc := json.NewEncoder(c.Writer)
+	started := false
+	for p, err := range seq {
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "could not stream passengers", "started", started, "error", err)
+			if !started {
+				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to retrieve passengers"})
+				return
+			}
+			return
+		}
+
+		if !started {
+			c.Header("Content-Type", contentType)
+			c.Status(http.StatusOK)
+			if !ndjson {
+				c.Writer.WriteString("[")
+			}
+			started = true
+		} else if !ndjson {
+			c.Writer.WriteString(",")
+		}
+
+		var v interface{} = p
+		if fields != nil {
+			v = fields.project(&p)
+		}
+		if err := enc.Encode(v); err != nil {
+			// The client has most likely gone away.
+			slog.DebugContext(c.Request.Context(), "could not write passenger", "error", err)
+			return
+		}
+	}
+
+	if !started {
+		c.Header("Content-Type", contentType)
+		c.Status(http.StatusOK)
+		if !ndjson {
+			c.Writer.WriteString("[")
+		}
+	}
+	if !ndjson {
+		c.Writer.WriteString("]")
+	}
+}
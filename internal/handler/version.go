@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	dataset "github.com/dhope-nagesh/titanic-go-service/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// buildInfo reads the module version and VCS revision stamped into the binary by the Go toolchain.
+var buildInfo = sync.OnceValue(func() model.VersionInfo {
+	v := model.VersionInfo{Version: "(devel)", GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	if info.Main.Version != "" {
+		v.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			v.Revision = s.Value
+		}
+	}
+	return v
+})
+
+// GetVersion reports the build and the dataset being served as a
+// model.VersionInfo. Like the probes it sits outside the API and needs no
+// credentials, so deployment tooling can check what a pod is running.
+func (h *APIHandler) GetVersion(c *gin.Context) {
+	info := buildInfo()
+	info.EmbeddedChecksum = dataset.Checksum()
+
+	version, err := h.Repo.Version(c.Request.Context())
+	if err != nil {
+		// The build is still worth reporting while the data source is down.
+		slog.WarnContext(c.Request.Context(), "could not read the dataset version", "error", err)
+	} else {
+		info.Dataset = &model.DatasetInfo{
+			Source:   h.sourceName,
+			Hash:     version.Hash,
+			Rows:     version.Rows,
+			Invalid:  version.Invalid,
+			LoadedAt: version.LoadedAt,
+		}
+		if !version.ModTime.IsZero() {
+			info.Dataset.ModifiedAt = &version.ModTime
+		}
+	}
+	c.JSON(http.StatusOK, info)
+}
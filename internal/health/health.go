@@ -0,0 +1,117 @@
+// Package health runs the dependency checks behind the readiness probe and
+// tracks maintenance work, such as imports, during which the service is not ready.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// CheckFunc reports whether a dependency is usable.
+type CheckFunc func(ctx context.Context) error
+
+// checkTimeout bounds each check, so a hung dependency fails the probe rather than stalling it.
+const checkTimeout = 2 * time.Second
+
+// Checker holds the registered dependency checks and the maintenance in progress.
+type Checker struct {
+	mu          sync.Mutex
+	checks      map[string]CheckFunc
+	maintenance map[string]int
+}
+
+// NewChecker returns a checker with no checks.
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]CheckFunc), maintenance: make(map[string]int)}
+}
+
+// Register adds or replaces the check for a dependency.
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Begin marks the service as not ready until the returned function is called,
+// e.g. while a dataset is being reloaded or migrated.
+func (c *Checker) Begin(reason string) (done func()) {
+	c.mu.Lock()
+	c.maintenance[reason]++
+	c.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.maintenance[reason]--; c.maintenance[reason] == 0 {
+				delete(c.maintenance, reason)
+			}
+		})
+	}
+}
+
+// Check runs every check concurrently and reports the service ready when all
+// of them pass and no maintenance is in progress.
+func (c *Checker) Check(ctx context.Context) model.HealthReport {
+	c.mu.Lock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	var maintenance []string
+	for reason := range c.maintenance {
+		maintenance = append(maintenance, reason)
+	}
+	c.mu.Unlock()
+	sort.Strings(maintenance)
+
+	results := make([]model.HealthCheck, 0, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := run(ctx, name, check)
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	report := model.HealthReport{Status: model.HealthOK, Checks: results, Maintenance: maintenance}
+	if len(maintenance) > 0 {
+		report.Status = model.HealthUnavailable
+	}
+	for _, r := range results {
+		if r.Status != model.HealthOK {
+			report.Status = model.HealthUnavailable
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, name string, check CheckFunc) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := model.HealthCheck{
+		Name:      name,
+		Status:    model.HealthOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = model.HealthUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
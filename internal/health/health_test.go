@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	c := NewChecker()
+	c.Register("data", func(ctx context.Context) error { return nil })
+
+	report := c.Check(context.Background())
+	assert.Equal(t, model.HealthOK, report.Status)
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, "data", report.Checks[0].Name)
+
+	c.Register("jobs", func(ctx context.Context) error { return errors.New("database is locked") })
+	report = c.Check(context.Background())
+	assert.Equal(t, model.HealthUnavailable, report.Status)
+	assert.Equal(t, model.HealthOK, report.Checks[0].Status)
+	assert.Equal(t, "jobs", report.Checks[1].Name)
+	assert.Equal(t, model.HealthUnavailable, report.Checks[1].Status)
+	assert.Equal(t, "database is locked", report.Checks[1].Error)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := NewChecker()
+	c.Register("hung", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := c.Check(ctx)
+	assert.Equal(t, model.HealthUnavailable, report.Status)
+	assert.Equal(t, context.Canceled.Error(), report.Checks[0].Error)
+}
+
+func TestChecker_Maintenance(t *testing.T) {
+	c := NewChecker()
+	first := c.Begin("import")
+	second := c.Begin("import")
+
+	report := c.Check(context.Background())
+	assert.Equal(t, model.HealthUnavailable, report.Status)
+	assert.Equal(t, []string{"import"}, report.Maintenance)
+
+	first()
+	first() // Calling done twice has no further effect.
+	assert.Equal(t, model.HealthUnavailable, c.Check(context.Background()).Status)
+
+	second()
+	report = c.Check(context.Background())
+	assert.Equal(t, model.HealthOK, report.Status)
+	assert.Empty(t, report.Maintenance)
+}
@@ -0,0 +1,373 @@
+// Package jobs runs long analyses asynchronously on a bounded pool of workers.
+// Job state is persisted, so queued and interrupted jobs resume after a restart.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/dhope-nagesh/titanic-go-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrUnknownKind is returned when submitting a job of a kind with no registered runner.
+	ErrUnknownKind = errors.New("unknown job kind")
+	// ErrQueueFull is returned when the queue is at capacity.
+	ErrQueueFull = errors.New("the job queue is full, try again later")
+	// ErrInvalidParams is returned when submitting a job whose parameters its kind rejects.
+	ErrInvalidParams = errors.New("invalid params")
+)
+
+// Task is the unit of work handed to a Runner.
+type Task struct {
+	ID     string
+	Params json.RawMessage
+	// ResultDir is where the runner may create result files.
+	ResultDir string
+
+	progress func(float64)
+}
+
+// SetProgress reports how far the task has got, from 0 to 1.
+func (t *Task) SetProgress(p float64) {
+	if t.progress != nil {
+		t.progress(p)
+	}
+}
+
+// Result is what a successful runner produces: a JSON value, a file, or both.
+type Result struct {
+	Value any
+	File  *model.JobFile
+}
+
+// Runner executes one kind of job. It must return promptly once ctx is done.
+type Runner func(ctx context.Context, task *Task) (Result, error)
+
+// Validator checks the parameters of one kind of job when it is submitted, so
+// that bad input is refused instead of queued to fail later.
+type Validator func(params json.RawMessage) error
+
+// kind is a registered job kind.
+type kind struct {
+	run      Runner
+	validate Validator
+}
+
+// Config tunes a Manager.
+type Config struct {
+	// Workers is the number of jobs run concurrently.
+	Workers int
+	// QueueSize bounds how many jobs may wait for a worker.
+	QueueSize int
+	// ResultTTL is how long finished jobs and their results are kept.
+	ResultTTL time.Duration
+	// ResultDir holds result files.
+	ResultDir string
+}
+
+// Manager queues jobs, runs them on a fixed pool of workers and expires old results.
+type Manager struct {
+	store *Store
+	cfg   Config
+	kinds map[string]kind
+
+	queue   chan string
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a manager backed by store. Register runners, then call Start.
+func NewManager(store *Store, cfg Config) (*Manager, error) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.ResultTTL <= 0 {
+		cfg.ResultTTL = 24 * time.Hour
+	}
+	if err := os.MkdirAll(cfg.ResultDir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create job result directory: %w", err)
+	}
+	return &Manager{
+		store:   store,
+		cfg:     cfg,
+		kinds:   make(map[string]kind),
+		queue:   make(chan string, cfg.QueueSize),
+		cancels: make(map[string]context.CancelFunc),
+	}, nil
+}
+
+// Register adds a runner for a job kind, and validate, if not nil, to check the
+// parameters of each job of that kind as it is submitted.
+func (m *Manager) Register(name string, runner Runner, validate Validator) {
+	m.kinds[name] = kind{run: runner, validate: validate}
+}
+
+// Kinds lists the registered job kinds.
+func (m *Manager) Kinds() []string {
+	kinds := make([]string, 0, len(m.kinds))
+	for kind := range m.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Start resumes jobs left over from a previous run and starts the workers and
+// the expiry janitor. Jobs that were running when the service stopped start over.
+func (m *Manager) Start() error {
+	ctx, stop := context.WithCancel(context.Background())
+	m.stop = stop
+
+	if err := m.store.requeue(ctx); err != nil {
+		return err
+	}
+	pending, err := m.store.byStatus(ctx, model.JobQueued)
+	if err != nil {
+		return err
+	}
+	if len(pending) > cap(m.queue) {
+		// Grow the queue so no resumed job is dropped.
+		m.queue = make(chan string, len(pending)+m.cfg.QueueSize)
+	}
+	for _, job := range pending {
+		m.queue <- job.ID
+	}
+
+	for i := 0; i < m.cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker(ctx)
+	}
+	m.wg.Add(1)
+	go m.janitor(ctx)
+	return nil
+}
+
+// Close cancels running jobs and waits for the workers to exit. Interrupted jobs
+// stay marked as running in the store and are requeued by the next Start.
+func (m *Manager) Close() {
+	if m.stop != nil {
+		m.stop()
+	}
+	m.wg.Wait()
+}
+
+// Submit queues a new job, once its kind has accepted params.
+func (m *Manager) Submit(ctx context.Context, kind string, params json.RawMessage) (model.Job, error) {
+	k, ok := m.kinds[kind]
+	if !ok {
+		return model.Job{}, fmt.Errorf("%w %q, valid kinds are: %s", ErrUnknownKind, kind, strings.Join(m.Kinds(), ", "))
+	}
+	if k.validate != nil {
+		if err := k.validate(params); err != nil {
+			return model.Job{}, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return model.Job{}, err
+	}
+	job := model.Job{ID: id, Kind: kind, Params: params, Status: model.JobQueued, CreatedAt: time.Now().UTC()}
+	if err := m.store.insert(ctx, job); err != nil {
+		return model.Job{}, err
+	}
+
+	select {
+	case m.queue <- id:
+		return job, nil
+	default:
+		m.store.delete(ctx, id)
+		return model.Job{}, ErrQueueFull
+	}
+}
+
+// Get returns the current state of a job.
+func (m *Manager) Get(ctx context.Context, id string) (model.Job, error) {
+	return m.store.get(ctx, id)
+}
+
+// Cancel stops a queued or running job. Canceling a finished job has no effect.
+func (m *Manager) Cancel(ctx context.Context, id string) (model.Job, error) {
+	job, err := m.store.get(ctx, id)
+	if err != nil {
+		return model.Job{}, err
+	}
+	if job.Status.Done() {
+		return job, nil
+	}
+
+	m.mu.Lock()
+	cancel, running := m.cancels[id]
+	m.mu.Unlock()
+	if running {
+		// The worker records the cancellation once the runner returns.
+		cancel()
+	} else if _, err := m.store.finish(ctx, m.finished(job, model.JobCanceled)); err != nil {
+		return model.Job{}, err
+	}
+	return m.store.get(ctx, id)
+}
+
+func (m *Manager) worker(ctx context.Context) {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(ctx, id)
+		}
+	}
+}
+
+// run executes one job, recording its progress and outcome in the store.
+func (m *Manager) run(ctx context.Context, id string) {
+	started, err := m.store.markRunning(ctx, id, time.Now().UTC())
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("could not start job", "job_id", id, "error", err)
+		}
+		return
+	}
+	if !started {
+		return // Canceled while queued.
+	}
+	job, err := m.store.get(ctx, id)
+	if err != nil {
+		slog.Error("could not load job", "job_id", id, "error", err)
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	m.cancels[id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.cancels, id)
+		m.mu.Unlock()
+		cancel()
+	}()
+
+	// Progress is persisted in whole-percent steps to keep writes cheap.
+	lastPercent := 0
+	task := &Task{ID: id, Params: job.Params, ResultDir: m.cfg.ResultDir, progress: func(p float64) {
+		percent := int(p * 100)
+		if percent <= lastPercent || percent > 100 {
+			return
+		}
+		lastPercent = percent
+		if err := m.store.setProgress(ctx, id, float64(percent)/100); err != nil {
+			slog.Warn("could not save job progress", "job_id", id, "error", err)
+		}
+	}}
+
+	spanCtx, span := tracing.Tracer().Start(jobCtx, "job."+job.Kind, trace.WithAttributes(
+		attribute.String("job.id", id), attribute.String("job.kind", job.Kind)))
+	result, err := m.kinds[job.Kind].run(spanCtx, task)
+	tracing.End(span, err)
+	switch {
+	case ctx.Err() != nil:
+		// The service is shutting down: leave the job running so it is requeued on restart.
+		if result.File != nil {
+			os.Remove(result.File.Path)
+		}
+		return
+	case jobCtx.Err() != nil:
+		job = m.finished(job, model.JobCanceled)
+	case err != nil:
+		job = m.finished(job, model.JobFailed)
+		job.Error = err.Error()
+	default:
+		job = m.finished(job, model.JobSucceeded)
+		job.Progress = 1
+		job.File = result.File
+		if result.Value != nil {
+			if job.Result, err = json.Marshal(result.Value); err != nil {
+				job = m.finished(job, model.JobFailed)
+				job.Error = "could not encode result: " + err.Error()
+			}
+		}
+	}
+	saved, err := m.store.finish(context.Background(), job)
+	if err != nil {
+		slog.Error("could not save job", "job_id", id, "status", string(job.Status), "error", err)
+	}
+	if (!saved || job.Status != model.JobSucceeded) && result.File != nil {
+		os.Remove(result.File.Path)
+	}
+}
+
+// finished stamps a job with a final status and its expiry time.
+func (m *Manager) finished(job model.Job, status model.JobStatus) model.Job {
+	now := time.Now().UTC()
+	expires := now.Add(m.cfg.ResultTTL)
+	job.Status = status
+	job.FinishedAt = &now
+	job.ExpiresAt = &expires
+	return job
+}
+
+// janitor periodically deletes expired jobs and their result files.
+func (m *Manager) janitor(ctx context.Context) {
+	defer m.wg.Done()
+	interval := min(max(m.cfg.ResultTTL/10, time.Second), time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.expire(ctx)
+		}
+	}
+}
+
+func (m *Manager) expire(ctx context.Context) {
+	jobs, err := m.store.expired(ctx, time.Now().UTC())
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("could not list expired jobs", "error", err)
+		}
+		return
+	}
+	for _, job := range jobs {
+		if job.File != nil {
+			if err := os.Remove(job.File.Path); err != nil && !os.IsNotExist(err) {
+				slog.Error("could not remove job result", "job_id", job.ID, "file", job.File.Path, "error", err)
+				continue
+			}
+		}
+		if err := m.store.delete(ctx, job.ID); err != nil {
+			slog.Error("could not delete job", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestManager(t *testing.T, dbPath string, cfg Config) *Manager {
+	store, err := OpenStore(dbPath)
+	assert.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	if cfg.ResultDir == "" {
+		cfg.ResultDir = filepath.Join(t.TempDir(), "results")
+	}
+	m, err := NewManager(store, cfg)
+	assert.NoError(t, err)
+	return m
+}
+
+// waitFor polls a job until it reaches a final state.
+func waitFor(t *testing.T, m *Manager, id string) model.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(context.Background(), id)
+		assert.NoError(t, err)
+		if job.Status.Done() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return model.Job{}
+}
+
+func newTestRepository(t *testing.T) data.PassengerRepository {
+	path := filepath.Join(t.TempDir(), "titanic.csv")
+	content := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n" +
+		"1,0,3,A,male,22,1,0,X,7.25,,S\n2,1,1,B,female,38,1,0,Y,71.28,C85,C\n" +
+		"3,1,3,C,female,26,0,0,Z,7.92,,S\n4,1,1,D,female,35,1,0,W,53.1,C123,S\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	repo, err := data.NewCSVRepository(path)
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestBootstrapJob(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{Workers: 2})
+	m.Register("bootstrap", BootstrapRunner(newTestRepository(t)), ValidateBootstrapParams)
+	assert.NoError(t, m.Start())
+	defer m.Close()
+
+	job, err := m.Submit(context.Background(), "bootstrap", json.RawMessage(`{"statistic":"survival_rate","resamples":500,"seed":7}`))
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobQueued, job.Status)
+
+	job = waitFor(t, m, job.ID)
+	assert.Equal(t, model.JobSucceeded, job.Status, job.Error)
+	assert.Equal(t, 1.0, job.Progress)
+	assert.NotNil(t, job.ExpiresAt)
+
+	var result BootstrapResult
+	assert.NoError(t, json.Unmarshal(job.Result, &result))
+	assert.Equal(t, 0.75, result.Estimate)
+	assert.Equal(t, 4, result.SampleSize)
+	assert.LessOrEqual(t, result.Lower, result.Estimate)
+	assert.GreaterOrEqual(t, result.Upper, result.Estimate)
+}
+
+func TestSubmitInvalidParams(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{})
+	m.Register("bootstrap", BootstrapRunner(newTestRepository(t)), ValidateBootstrapParams)
+	m.Register("cross_validation", CrossValidationRunner(newTestRepository(t)), ValidateCrossValidationParams)
+	m.Register("export", ExportRunner(newTestRepository(t)), ValidateExportParams)
+
+	for _, tc := range []struct{ kind, params, want string }{
+		{"bootstrap", `{"statistic":"median_height"}`, "median_height"},
+		{"bootstrap", `{"resamples":0}`, "resamples must be between"},
+		{"bootstrap", `{"resample":5000}`, "unknown field"},
+		{"bootstrap", `[1]`, "cannot unmarshal"},
+		{"cross_validation", `{"folds":1}`, "folds must be between"},
+		{"export", `{"format":"docx"}`, "unsupported export format"},
+	} {
+		_, err := m.Submit(context.Background(), tc.kind, json.RawMessage(tc.params))
+		assert.ErrorIs(t, err, ErrInvalidParams, tc.params)
+		assert.ErrorContains(t, err, tc.want, tc.params)
+	}
+	jobs, err := m.store.byStatus(context.Background(), model.JobQueued)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs, "refused jobs are not stored")
+}
+
+func TestCrossValidationJob(t *testing.T) {
+	repo, err := data.NewEmbeddedRepository()
+	assert.NoError(t, err)
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{})
+	m.Register("cross_validation", CrossValidationRunner(repo), ValidateCrossValidationParams)
+	assert.NoError(t, m.Start())
+	defer m.Close()
+
+	job, err := m.Submit(context.Background(), "cross_validation", json.RawMessage(`{"folds":5,"seed":7}`))
+	assert.NoError(t, err)
+	job = waitFor(t, m, job.ID)
+	assert.Equal(t, model.JobSucceeded, job.Status, job.Error)
+
+	var result CrossValidationResult
+	assert.NoError(t, json.Unmarshal(job.Result, &result))
+	assert.Equal(t, 891, result.SampleSize)
+	assert.Len(t, result.FoldAccuracy, 5)
+	assert.InDelta(t, 0.616, result.Baseline, 0.001, "549 of 891 passengers died")
+	assert.Greater(t, result.Accuracy, 0.75, "the model must clearly beat the baseline")
+	assert.Less(t, result.Accuracy, 0.85)
+}
+
+func TestExportJob(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{})
+	m.Register("export", ExportRunner(newTestRepository(t)), ValidateExportParams)
+	assert.NoError(t, m.Start())
+	defer m.Close()
+
+	job, err := m.Submit(context.Background(), "export", json.RawMessage(`{"format":"xlsx"}`))
+	assert.NoError(t, err)
+
+	job = waitFor(t, m, job.ID)
+	assert.Equal(t, model.JobSucceeded, job.Status, job.Error)
+	assert.NotNil(t, job.File)
+	assert.Regexp(t, `^titanic-[0-9a-f]{12}\.xlsx$`, job.File.Name)
+	assert.FileExists(t, job.File.Path)
+}
+
+func TestSubmitUnknownKind(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{})
+	m.Register("bootstrap", BootstrapRunner(newTestRepository(t)), ValidateBootstrapParams)
+
+	_, err := m.Submit(context.Background(), "train", nil)
+	assert.ErrorIs(t, err, ErrUnknownKind)
+	assert.Contains(t, err.Error(), "bootstrap")
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{QueueSize: 1})
+	m.Register("noop", func(ctx context.Context, task *Task) (Result, error) { return Result{}, nil }, nil)
+
+	// Without Start nothing drains the queue.
+	_, err := m.Submit(context.Background(), "noop", nil)
+	assert.NoError(t, err)
+	_, err = m.Submit(context.Background(), "noop", nil)
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{})
+	started := make(chan struct{})
+	m.Register("slow", func(ctx context.Context, task *Task) (Result, error) {
+		close(started)
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}, nil)
+	assert.NoError(t, m.Start())
+	defer m.Close()
+
+	job, err := m.Submit(context.Background(), "slow", nil)
+	assert.NoError(t, err)
+	<-started
+
+	_, err = m.Cancel(context.Background(), job.ID)
+	assert.NoError(t, err)
+	job = waitFor(t, m, job.ID)
+	assert.Equal(t, model.JobCanceled, job.Status)
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{})
+	ran := false
+	m.Register("noop", func(ctx context.Context, task *Task) (Result, error) {
+		ran = true
+		return Result{}, nil
+	}, nil)
+
+	job, err := m.Submit(context.Background(), "noop", nil)
+	assert.NoError(t, err)
+	job, err = m.Cancel(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobCanceled, job.Status)
+
+	assert.NoError(t, m.Start())
+	m.Close()
+	assert.False(t, ran, "a canceled job must not run")
+}
+
+func TestJobsSurviveRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "jobs.db")
+	first := newTestManager(t, dbPath, Config{})
+	first.Register("noop", func(ctx context.Context, task *Task) (Result, error) { return Result{Value: "done"}, nil }, nil)
+	queued, err := first.Submit(context.Background(), "noop", nil)
+	assert.NoError(t, err)
+	// The first process goes away before any worker picks the job up.
+
+	second := newTestManager(t, dbPath, Config{})
+	second.Register("noop", func(ctx context.Context, task *Task) (Result, error) { return Result{Value: "done"}, nil }, nil)
+	assert.NoError(t, second.Start())
+	defer second.Close()
+
+	job := waitFor(t, second, queued.ID)
+	assert.Equal(t, model.JobSucceeded, job.Status)
+	assert.JSONEq(t, `"done"`, string(job.Result))
+}
+
+func TestExpiredJobsAreRemoved(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, filepath.Join(t.TempDir(), "jobs.db"), Config{ResultTTL: time.Millisecond})
+	m.Register("export", ExportRunner(newTestRepository(t)), ValidateExportParams)
+	assert.NoError(t, m.Start())
+	defer m.Close()
+
+	job, err := m.Submit(ctx, "export", nil)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		done, err := m.store.byStatus(ctx, model.JobSucceeded)
+		return err == nil && len(done) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = m.Get(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "expired jobs must be hidden even before the janitor runs")
+
+	// The janitor deletes the job and its result file.
+	assert.Eventually(t, func() bool {
+		done, err := m.store.byStatus(ctx, model.JobSucceeded)
+		entries, _ := os.ReadDir(m.cfg.ResultDir)
+		return err == nil && len(done) == 0 && len(entries) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,217 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/export"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"gonum.org/v1/gonum/stat"
+)
+
+// decodeParams unmarshals optional job parameters into v, leaving defaults for
+// absent fields. Unknown fields are rejected, so that a misspelt parameter is
+// not silently replaced by its default.
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// ExportParams are the parameters of an "export" job.
+type ExportParams struct {
+	Format string `json:"format"`
+}
+
+func parseExportParams(raw json.RawMessage) (export.Format, error) {
+	params := ExportParams{Format: string(export.CSV)}
+	if err := decodeParams(raw, &params); err != nil {
+		return "", err
+	}
+	return export.ParseFormat(params.Format)
+}
+
+// ValidateExportParams checks the parameters of an "export" job.
+func ValidateExportParams(raw json.RawMessage) error {
+	_, err := parseExportParams(raw)
+	return err
+}
+
+// ExportRunner writes the whole dataset to a result file, like GET /export but
+// without holding a request open while it does so.
+func ExportRunner(repo data.PassengerRepository) Runner {
+	return func(ctx context.Context, task *Task) (Result, error) {
+		format, err := parseExportParams(task.Params)
+		if err != nil {
+			return Result{}, err
+		}
+		version, err := repo.Version(ctx)
+		if err != nil {
+			return Result{}, err
+		}
+
+		path := filepath.Join(task.ResultDir, task.ID+"."+string(format))
+		file, err := os.Create(path)
+		if err != nil {
+			return Result{}, err
+		}
+		result := Result{File: &model.JobFile{Path: path, Name: format.Filename(version.Hash), ContentType: format.ContentType()}}
+		if err := export.Write(file, format, withProgress(repo.Passengers(ctx), version.Rows, task)); err != nil {
+			file.Close()
+			return result, err
+		}
+		return result, file.Close()
+	}
+}
+
+// withProgress reports the share of total rows consumed from seq as task progress.
+func withProgress(seq iter.Seq2[model.Passenger, error], total int, task *Task) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		n := 0
+		for p, err := range seq {
+			if !yield(p, err) {
+				return
+			}
+			n++
+			if total > 0 {
+				task.SetProgress(float64(n) / float64(total))
+			}
+		}
+	}
+}
+
+// BootstrapParams are the parameters of a "bootstrap" job.
+type BootstrapParams struct {
+	// Statistic is one of survival_rate, mean_fare or mean_age.
+	Statistic string `json:"statistic"`
+	// Resamples is the number of bootstrap resamples to draw.
+	Resamples int `json:"resamples"`
+	// Confidence is the coverage of the interval, between 0 and 1.
+	Confidence float64 `json:"confidence"`
+	// Seed makes the resampling reproducible when set.
+	Seed *uint64 `json:"seed,omitempty"`
+}
+
+// BootstrapResult is the outcome of a "bootstrap" job.
+type BootstrapResult struct {
+	Statistic  string  `json:"statistic"`
+	Estimate   float64 `json:"estimate"`
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Confidence float64 `json:"confidence"`
+	Resamples  int     `json:"resamples"`
+	SampleSize int     `json:"sampleSize"`
+}
+
+// maxResamples bounds the work a single bootstrap job may request.
+const maxResamples = 100000
+
+// statistics extract the sample for each supported bootstrap statistic. Passengers
+// with a missing value are left out of the sample.
+var statistics = map[string]func(p *model.Passenger) (float64, bool){
+	"survival_rate": func(p *model.Passenger) (float64, bool) { return float64(p.Survived), true },
+	"mean_fare": func(p *model.Passenger) (float64, bool) {
+		if p.Fare == nil {
+			return 0, false
+		}
+		return *p.Fare, true
+	},
+	"mean_age": func(p *model.Passenger) (float64, bool) {
+		if p.Age == nil {
+			return 0, false
+		}
+		return *p.Age, true
+	},
+}
+
+func parseBootstrapParams(raw json.RawMessage) (BootstrapParams, error) {
+	params := BootstrapParams{Statistic: "survival_rate", Resamples: 1000, Confidence: 0.95}
+	if err := decodeParams(raw, &params); err != nil {
+		return params, err
+	}
+	if _, ok := statistics[params.Statistic]; !ok {
+		return params, fmt.Errorf("unknown statistic %q, valid statistics are: mean_age, mean_fare, survival_rate", params.Statistic)
+	}
+	if params.Resamples < 1 || params.Resamples > maxResamples {
+		return params, fmt.Errorf("resamples must be between 1 and %d", maxResamples)
+	}
+	if params.Confidence <= 0 || params.Confidence >= 1 {
+		return params, fmt.Errorf("confidence must be between 0 and 1")
+	}
+	return params, nil
+}
+
+// ValidateBootstrapParams checks the parameters of a "bootstrap" job.
+func ValidateBootstrapParams(raw json.RawMessage) error {
+	_, err := parseBootstrapParams(raw)
+	return err
+}
+
+// BootstrapRunner computes a percentile bootstrap confidence interval for the mean
+// of a passenger attribute.
+func BootstrapRunner(repo data.PassengerRepository) Runner {
+	return func(ctx context.Context, task *Task) (Result, error) {
+		params, err := parseBootstrapParams(task.Params)
+		if err != nil {
+			return Result{}, err
+		}
+		extract := statistics[params.Statistic]
+
+		var sample []float64
+		for p, err := range repo.Passengers(ctx) {
+			if err != nil {
+				return Result{}, err
+			}
+			if v, ok := extract(&p); ok {
+				sample = append(sample, v)
+			}
+		}
+		if len(sample) == 0 {
+			return Result{}, fmt.Errorf("no passengers have a value for %s", params.Statistic)
+		}
+
+		seed := rand.Uint64()
+		if params.Seed != nil {
+			seed = *params.Seed
+		}
+		rng := rand.New(rand.NewPCG(seed, seed))
+
+		estimates := make([]float64, params.Resamples)
+		for i := range estimates {
+			if i%100 == 0 {
+				if err := ctx.Err(); err != nil {
+					return Result{}, err
+				}
+				task.SetProgress(float64(i) / float64(params.Resamples))
+			}
+			sum := 0.0
+			for range sample {
+				sum += sample[rng.IntN(len(sample))]
+			}
+			estimates[i] = sum / float64(len(sample))
+		}
+		sort.Float64s(estimates)
+
+		alpha := (1 - params.Confidence) / 2
+		return Result{Value: BootstrapResult{
+			Statistic:  params.Statistic,
+			Estimate:   stat.Mean(sample, nil),
+			Lower:      stat.Quantile(alpha, stat.Empirical, estimates, nil),
+			Upper:      stat.Quantile(1-alpha, stat.Empirical, estimates, nil),
+			Confidence: params.Confidence,
+			Resamples:  params.Resamples,
+			SampleSize: len(sample),
+		}}, nil
+	}
+}
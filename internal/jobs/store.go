@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrNotFound is returned for unknown or expired job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// Store persists jobs in a SQLite database so they survive a restart.
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens (creating if needed) the job database at path.
+func OpenStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// Serialise writers: workers update progress concurrently.
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS jobs (
+		id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		params TEXT,
+		status TEXT NOT NULL,
+		progress REAL NOT NULL DEFAULT 0,
+		result TEXT,
+		result_path TEXT,
+		result_name TEXT,
+		result_type TEXT,
+		error TEXT,
+		created_at DATETIME NOT NULL,
+		started_at DATETIME,
+		finished_at DATETIME,
+		expires_at DATETIME
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Ping checks the database connection.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+const jobColumns = "id, kind, params, status, progress, result, result_path, result_name, result_type, error, " +
+	"created_at, started_at, finished_at, expires_at"
+
+func (s *Store) insert(ctx context.Context, job model.Job) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO jobs (id, kind, params, status, progress, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		job.ID, job.Kind, nullableBytes(job.Params), job.Status, job.Progress, job.CreatedAt)
+	return err
+}
+
+// get loads a job. Expired jobs are reported as not found even before the janitor removes them.
+func (s *Store) get(ctx context.Context, id string) (model.Job, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ?", id)
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Job{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Job{}, err
+	}
+	if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+		return model.Job{}, ErrNotFound
+	}
+	return job, nil
+}
+
+// byStatus lists jobs in the given states, oldest first.
+func (s *Store) byStatus(ctx context.Context, statuses ...model.JobStatus) ([]model.Job, error) {
+	var jobs []model.Job
+	for _, status := range statuses {
+		rows, err := s.db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE status = ? ORDER BY created_at", status)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			job, err := scanJob(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			jobs = append(jobs, job)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// markRunning moves a queued job to running. It reports false if the job is no
+// longer queued, for example because it was canceled while waiting.
+func (s *Store) markRunning(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ?, started_at = ? WHERE id = ? AND status = ?",
+		model.JobRunning, at, id, model.JobQueued)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *Store) setProgress(ctx context.Context, id string, progress float64) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET progress = ? WHERE id = ? AND status = ?", progress, id, model.JobRunning)
+	return err
+}
+
+// finish records the final state of a job. It reports false if the job had
+// already finished, for example because it was canceled in the meantime.
+func (s *Store) finish(ctx context.Context, job model.Job) (bool, error) {
+	var path, name, contentType sql.NullString
+	if job.File != nil {
+		path = sql.NullString{String: job.File.Path, Valid: true}
+		name = sql.NullString{String: job.File.Name, Valid: true}
+		contentType = sql.NullString{String: job.File.ContentType, Valid: true}
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ?, progress = ?, result = ?, result_path = ?, result_name = ?, "+
+		"result_type = ?, error = ?, finished_at = ?, expires_at = ? WHERE id = ? AND status IN (?, ?)",
+		job.Status, job.Progress, nullableBytes(job.Result), path, name, contentType, nullableString(job.Error),
+		job.FinishedAt, job.ExpiresAt, job.ID, model.JobQueued, model.JobRunning)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// requeue returns jobs that were running when the service stopped to the queue.
+func (s *Store) requeue(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ?, progress = 0, started_at = NULL WHERE status = ?",
+		model.JobQueued, model.JobRunning)
+	return err
+}
+
+// expired lists finished jobs whose expiry time has passed.
+func (s *Store) expired(ctx context.Context, now time.Time) ([]model.Job, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE expires_at IS NOT NULL AND expires_at < ?", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []model.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *Store) delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM jobs WHERE id = ?", id)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (model.Job, error) {
+	var job model.Job
+	var params, result, path, name, contentType, errMsg sql.NullString
+	var startedAt, finishedAt, expiresAt sql.NullTime
+	err := row.Scan(&job.ID, &job.Kind, &params, &job.Status, &job.Progress, &result, &path, &name, &contentType,
+		&errMsg, &job.CreatedAt, &startedAt, &finishedAt, &expiresAt)
+	if err != nil {
+		return model.Job{}, err
+	}
+	if params.Valid {
+		job.Params = []byte(params.String)
+	}
+	if result.Valid {
+		job.Result = []byte(result.String)
+	}
+	if path.Valid {
+		job.File = &model.JobFile{Path: path.String, Name: name.String, ContentType: contentType.String}
+	}
+	job.Error = errMsg.String
+	job.StartedAt = timePtr(startedAt)
+	job.FinishedAt = timePtr(finishedAt)
+	job.ExpiresAt = timePtr(expiresAt)
+	return job, nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullableBytes(b []byte) sql.NullString {
+	return sql.NullString{String: string(b), Valid: len(b) > 0}
+}
+
+func nullableString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// capture makes a JSON logger writing to the returned buffer the default for the test.
+func capture(t *testing.T, level slog.Level) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// lines decodes the JSON log lines written to buf.
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, level)
+	level, err = ParseLevel("DEBUG")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+	_, err = ParseLevel("loud")
+	assert.ErrorContains(t, err, "invalid log level")
+}
+
+func TestMiddleware(t *testing.T) {
+	buf := capture(t, slog.LevelInfo)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), AccessLog(), Recovery())
+	router.GET("/passengers/:id", func(c *gin.Context) {
+		slog.InfoContext(c.Request.Context(), "looking up passenger")
+		c.Status(http.StatusOK)
+	})
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	t.Run("honours the client's ID", func(t *testing.T) {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/passengers/1", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+		entries := lines(t, buf)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "looking up passenger", entries[0]["msg"])
+		assert.Equal(t, "request", entries[1]["msg"])
+		for _, entry := range entries {
+			assert.Equal(t, "abc-123", entry["request_id"])
+		}
+		assert.Equal(t, "/passengers/:id", entries[1]["route"])
+		assert.Equal(t, 200.0, entries[1]["status"])
+		assert.NotContains(t, entries[1], "client_subject")
+	})
+
+	t.Run("logs the client certificate", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/passengers/1", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "dashboard", Organization: []string{"Example"}}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "CN=dashboard,O=Example", lines(t, buf)[1]["client_subject"])
+	})
+
+	for name, header := range map[string]string{"generates one": "", "replaces an invalid ID": "two\nlines"} {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/passengers/1", nil)
+			req.Header.Set(RequestIDHeader, header)
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			assert.Len(t, id, 32)
+			assert.Equal(t, id, lines(t, buf)[1]["request_id"])
+		})
+	}
+
+	t.Run("logs panics", func(t *testing.T) {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		entries := lines(t, buf)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "panic serving request", entries[0]["msg"])
+		assert.Equal(t, "boom", entries[0]["error"])
+		assert.Contains(t, entries[0]["stack"], "logging.TestMiddleware", "the stack reaches the panicking handler")
+		assert.Equal(t, "ERROR", entries[1]["level"])
+		assert.Equal(t, entries[0]["request_id"], entries[1]["request_id"])
+	})
+}
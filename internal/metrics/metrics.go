@@ -0,0 +1,222 @@
+// Package metrics exposes Prometheus metrics for HTTP requests, repository
+// calls and the dataset being served.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/cache"
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "titanic"
+
+// Metrics holds the service's collectors in a registry of its own, so tests
+// can create as many as they like.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	repoDuration *prometheus.HistogramVec
+	repoErrors   *prometheus.CounterVec
+}
+
+// New creates the collectors, including the Go runtime and process ones.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of repository operations. Streaming operations include the time the caller spends consuming them.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "errors_total",
+			Help:      "Failed repository operations.",
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		m.httpRequests, m.httpDuration, m.repoDuration, m.repoErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records the count and latency of each request under its route
+// pattern, e.g. /api/v1/passengers/:id, so IDs do not multiply the series.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveDataset exports gauges for the dataset served by repo. They are read
+// from repo.Version at scrape time, which is cheap while the data is unchanged.
+func (m *Metrics) ObserveDataset(repo data.PassengerRepository) {
+	m.registry.MustRegister(&datasetCollector{repo: repo})
+}
+
+var (
+	datasetRowsDesc = prometheus.NewDesc(namespace+"_dataset_rows",
+		"Passengers in the dataset being served.", nil, nil)
+	datasetLoadedDesc = prometheus.NewDesc(namespace+"_dataset_last_load_timestamp_seconds",
+		"When the dataset was last read from the data source.", nil, nil)
+	datasetInvalidDesc = prometheus.NewDesc(namespace+"_dataset_parse_errors",
+		"Records in the data source that could not be parsed and are skipped.", nil, nil)
+	datasetUpDesc = prometheus.NewDesc(namespace+"_dataset_up",
+		"Whether the dataset could be read at the last scrape.", nil, nil)
+)
+
+type datasetCollector struct {
+	repo data.PassengerRepository
+}
+
+func (d *datasetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- datasetRowsDesc
+	ch <- datasetLoadedDesc
+	ch <- datasetInvalidDesc
+	ch <- datasetUpDesc
+}
+
+func (d *datasetCollector) Collect(ch chan<- prometheus.Metric) {
+	v, err := d.repo.Version(context.Background())
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(datasetUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(datasetUpDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(datasetRowsDesc, prometheus.GaugeValue, float64(v.Rows))
+	ch <- prometheus.MustNewConstMetric(datasetInvalidDesc, prometheus.GaugeValue, float64(v.Invalid))
+	if !v.LoadedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(datasetLoadedDesc, prometheus.GaugeValue, float64(v.LoadedAt.UnixNano())/1e9)
+	}
+}
+
+// ObserveFailover exports which backend of a failover repository served each
+// call, the state of its circuit breaker and the outcome of shadow reads.
+func (m *Metrics) ObserveFailover(repo *data.FailoverRepository) {
+	m.registry.MustRegister(&failoverCollector{repo: repo})
+}
+
+var (
+	failoverServedDesc = prometheus.NewDesc(namespace+"_failover_served_total",
+		"Repository calls answered by each backend of the failover data source.", []string{"backend", "operation"}, nil)
+	failoverFallbacksDesc = prometheus.NewDesc(namespace+"_failover_fallbacks_total",
+		"Calls that failed on the primary backend and were retried on the fallback.", nil, nil)
+	failoverOpenDesc = prometheus.NewDesc(namespace+"_failover_circuit_open",
+		"Whether calls currently bypass the primary backend.", nil, nil)
+	failoverMismatchDesc = prometheus.NewDesc(namespace+"_failover_shadow_mismatches_total",
+		"Shadow reads whose result on the fallback differed from the primary.", []string{"operation"}, nil)
+	failoverShadowErrorsDesc = prometheus.NewDesc(namespace+"_failover_shadow_errors_total",
+		"Shadow reads that failed on the fallback.", nil, nil)
+	failoverPrimaryImportsDesc = prometheus.NewDesc(namespace+"_failover_primary_only_imports_total",
+		"Imports applied to the primary backend and not to the fallback.", nil, nil)
+)
+
+type failoverCollector struct {
+	repo *data.FailoverRepository
+}
+
+func (f *failoverCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- failoverServedDesc
+	ch <- failoverFallbacksDesc
+	ch <- failoverOpenDesc
+	ch <- failoverMismatchDesc
+	ch <- failoverShadowErrorsDesc
+	ch <- failoverPrimaryImportsDesc
+}
+
+func (f *failoverCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := f.repo.Stats()
+	for backend, ops := range stats.Served {
+		for op, n := range ops {
+			ch <- prometheus.MustNewConstMetric(failoverServedDesc, prometheus.CounterValue, float64(n), backend, op)
+		}
+	}
+	for op, n := range stats.ShadowMismatches {
+		ch <- prometheus.MustNewConstMetric(failoverMismatchDesc, prometheus.CounterValue, float64(n), op)
+	}
+	open := 0.0
+	if stats.CircuitOpen {
+		open = 1
+	}
+	ch <- prometheus.MustNewConstMetric(failoverOpenDesc, prometheus.GaugeValue, open)
+	ch <- prometheus.MustNewConstMetric(failoverFallbacksDesc, prometheus.CounterValue, float64(stats.Fallbacks))
+	ch <- prometheus.MustNewConstMetric(failoverShadowErrorsDesc, prometheus.CounterValue, float64(stats.ShadowErrors))
+	ch <- prometheus.MustNewConstMetric(failoverPrimaryImportsDesc, prometheus.CounterValue, float64(stats.PrimaryOnlyImports))
+}
+
+// ObserveCache exports the hit, miss and eviction counts of the repository cache.
+func (m *Metrics) ObserveCache(repo *cache.Repository) {
+	m.registry.MustRegister(&cacheCollector{repo: repo})
+}
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(namespace+"_cache_hits_total",
+		"Repository calls and computed statistics served from the cache.", nil, nil)
+	cacheMissesDesc = prometheus.NewDesc(namespace+"_cache_misses_total",
+		"Repository calls and computed statistics not found in the cache.", nil, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(namespace+"_cache_evictions_total",
+		"Cache entries dropped to stay within the size bound.", nil, nil)
+	cacheEntriesDesc = prometheus.NewDesc(namespace+"_cache_entries",
+		"Entries currently in the cache.", nil, nil)
+)
+
+type cacheCollector struct {
+	repo *cache.Repository
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheEntriesDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.repo.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries))
+}
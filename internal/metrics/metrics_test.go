@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/cache"
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepository embeds the interface so only the methods under test need bodies.
+type fakeRepository struct {
+	data.PassengerRepository
+	version data.DatasetVersion
+	err     error
+}
+
+func (f *fakeRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &model.Passenger{PassengerID: id}, nil
+}
+
+func (f *fakeRepository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	return f.version, f.err
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/passengers/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/passengers/1", "/passengers/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/passengers/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	m := New()
+	fake := &fakeRepository{}
+	repo := m.InstrumentRepository(fake)
+
+	_, err := repo.GetPassengerByID(context.Background(), 1)
+	assert.NoError(t, err)
+	fake.err = errors.New("disk on fire")
+	_, err = repo.GetPassengerByID(context.Background(), 1)
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.repoDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repoErrors.WithLabelValues("GetPassengerByID")))
+
+	_, err = repo.(data.Importer).Import(context.Background(), data.ImportUpsert, nil)
+	assert.ErrorIs(t, err, data.ErrImportNotSupported)
+}
+
+func TestObserveDataset(t *testing.T) {
+	m := New()
+	fake := &fakeRepository{version: data.DatasetVersion{Rows: 891, Invalid: 2, LoadedAt: time.Unix(1700000000, 0)}}
+	m.ObserveDataset(fake)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"titanic_dataset_rows 891",
+		"titanic_dataset_parse_errors 2",
+		"titanic_dataset_last_load_timestamp_seconds 1.7e+09",
+		"titanic_dataset_up 1",
+	} {
+		assert.Contains(t, body, want)
+	}
+
+	fake.err = errors.New("file missing")
+	w = httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), "titanic_dataset_up 0")
+	assert.False(t, strings.Contains(w.Body.String(), "titanic_dataset_rows"))
+}
+
+func TestObserveFailover(t *testing.T) {
+	m := New()
+	primary := &fakeRepository{err: errors.New("database is locked")}
+	repo := data.NewFailoverRepository(data.Backend{Name: "sqlite", Repo: primary}, data.Backend{Name: "csv", Repo: &fakeRepository{}},
+		data.FailoverConfig{FailureThreshold: 1})
+	m.ObserveFailover(repo)
+
+	_, err := repo.GetPassengerByID(context.Background(), 1)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`titanic_failover_served_total{backend="csv",operation="GetPassengerByID"} 1`,
+		"titanic_failover_fallbacks_total 1",
+		"titanic_failover_circuit_open 1",
+		"titanic_failover_shadow_errors_total 0",
+		"titanic_failover_primary_only_imports_total 0",
+	} {
+		assert.Contains(t, body, want)
+	}
+}
+
+func TestObserveCache(t *testing.T) {
+	m := New()
+	repo := cache.NewRepository(&fakeRepository{version: data.DatasetVersion{Hash: "v1"}}, cache.Config{})
+	m.ObserveCache(repo)
+
+	for range 2 {
+		_, err := repo.GetPassengerByID(context.Background(), 1)
+		assert.NoError(t, err)
+	}
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{"titanic_cache_hits_total 1", "titanic_cache_misses_total 1", "titanic_cache_entries 1"} {
+		assert.Contains(t, w.Body.String(), want)
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// instrumentedRepository records the latency and errors of every call to the
+// repository it wraps.
+type instrumentedRepository struct {
+	next data.PassengerRepository
+	m    *Metrics
+}
+
+// InstrumentRepository wraps repo so that its calls are measured. Imports are
+// forwarded when repo supports them.
+func (m *Metrics) InstrumentRepository(repo data.PassengerRepository) data.PassengerRepository {
+	return &instrumentedRepository{next: repo, m: m}
+}
+
+// observe records one call that started at start.
+func (r *instrumentedRepository) observe(op string, start time.Time, err error) {
+	r.m.repoDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		r.m.repoErrors.WithLabelValues(op).Inc()
+	}
+}
+
+func (r *instrumentedRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	start := time.Now()
+	passengers, err := r.next.GetAllPassengers(ctx)
+	r.observe("GetAllPassengers", start, err)
+	return passengers, err
+}
+
+func (r *instrumentedRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	start := time.Now()
+	p, err := r.next.GetPassengerByID(ctx, id)
+	r.observe("GetPassengerByID", start, err)
+	return p, err
+}
+
+func (r *instrumentedRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	start := time.Now()
+	passengers, err := r.next.GetPassengersByIDs(ctx, ids)
+	r.observe("GetPassengersByIDs", start, err)
+	return passengers, err
+}
+
+func (r *instrumentedRepository) GetFares(ctx context.Context) ([]float64, error) {
+	start := time.Now()
+	fares, err := r.next.GetFares(ctx)
+	r.observe("GetFares", start, err)
+	return fares, err
+}
+
+// Passengers measures the iteration from the first record to the last, or to
+// the point where the caller stops.
+func (r *instrumentedRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		start := time.Now()
+		var failed error
+		defer func() { r.observe("Passengers", start, failed) }()
+		for p, err := range r.next.Passengers(ctx) {
+			if err != nil {
+				failed = err
+			}
+			if !yield(p, err) {
+				return
+			}
+		}
+	}
+}
+
+func (r *instrumentedRepository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	start := time.Now()
+	v, err := r.next.Version(ctx)
+	r.observe("Version", start, err)
+	return v, err
+}
+
+func (r *instrumentedRepository) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.Ping(ctx)
+	r.observe("Ping", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Close() error {
+	return r.next.Close()
+}
+
+// Import implements data.Importer, reporting data.ErrImportNotSupported when
+// the wrapped repository cannot import.
+func (r *instrumentedRepository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	importer, ok := r.next.(data.Importer)
+	if !ok {
+		return model.ImportSummary{}, data.ErrImportNotSupported
+	}
+	start := time.Now()
+	summary, err := importer.Import(ctx, mode, records)
+	r.observe("Import", start, err)
+	return summary, err
+}
@@ -0,0 +1,23 @@
+package model
+
+// Health statuses reported by the readiness probe.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthReport is the detailed readiness report returned by /readyz?verbose.
+type HealthReport struct {
+	Status string        `json:"status" example:"ok"`
+	Checks []HealthCheck `json:"checks"`
+	// Maintenance lists work in progress, such as an import, that makes the service not ready.
+	Maintenance []string `json:"maintenance,omitempty"`
+}
+
+// HealthCheck is the result of checking one dependency.
+type HealthCheck struct {
+	Name      string  `json:"name" example:"data"`
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latencyMs" example:"0.42"`
+	Error     string  `json:"error,omitempty"`
+}
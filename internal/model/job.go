@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobStatus is the lifecycle state of an asynchronous job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// Done reports whether the job has reached a final state.
+func (s JobStatus) Done() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// JobRequest is the body used to submit a job.
+type JobRequest struct {
+	Kind   string          `json:"kind" binding:"required" example:"bootstrap"`
+	Params json.RawMessage `json:"params" swaggertype:"object"`
+}
+
+// Job describes an asynchronous job and, once it has succeeded, its result.
+// Jobs producing a file expose it through ResultURL instead of Result.
+type Job struct {
+	ID         string          `json:"id" example:"4f1c2a7d9e0b3c5a"`
+	Kind       string          `json:"kind" example:"bootstrap"`
+	Params     json.RawMessage `json:"params,omitempty" swaggertype:"object"`
+	Status     JobStatus       `json:"status" example:"running"`
+	Progress   float64         `json:"progress" example:"0.42"`
+	Result     json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	ResultURL  string          `json:"resultUrl,omitempty" example:"/api/v1/jobs/4f1c2a7d9e0b3c5a/result"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	ExpiresAt  *time.Time      `json:"expiresAt,omitempty"`
+
+	// File locates a result file on the server. It is never sent to clients.
+	File *JobFile `json:"-"`
+}
+
+// JobFile describes a file produced by a job.
+type JobFile struct {
+	Path        string
+	Name        string
+	ContentType string
+}
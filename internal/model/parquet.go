@@ -0,0 +1,55 @@
+package model
+
+// ParquetPassenger is the Parquet row layout of a Passenger. Columns use the
+// names of the original Titanic CSV, and the fields that may be missing there
+// (Age, Fare, Cabin, Embarked) are optional columns.
+type ParquetPassenger struct {
+	PassengerID int64    `parquet:"PassengerId"`
+	Survived    int32    `parquet:"Survived"`
+	Pclass      int32    `parquet:"Pclass"`
+	Name        string   `parquet:"Name"`
+	Sex         string   `parquet:"Sex"`
+	Age         *float64 `parquet:"Age,optional"`
+	SibSp       int32    `parquet:"SibSp"`
+	Parch       int32    `parquet:"Parch"`
+	Ticket      string   `parquet:"Ticket"`
+	Fare        *float64 `parquet:"Fare,optional"`
+	Cabin       *string  `parquet:"Cabin,optional"`
+	Embarked    *string  `parquet:"Embarked,optional"`
+}
+
+// NewParquetPassenger converts a Passenger to its Parquet row.
+func NewParquetPassenger(p Passenger) ParquetPassenger {
+	return ParquetPassenger{
+		PassengerID: int64(p.PassengerID),
+		Survived:    int32(p.Survived),
+		Pclass:      int32(p.Pclass),
+		Name:        p.Name,
+		Sex:         p.Sex,
+		Age:         p.Age,
+		SibSp:       int32(p.SibSp),
+		Parch:       int32(p.Parch),
+		Ticket:      p.Ticket,
+		Fare:        p.Fare,
+		Cabin:       p.Cabin,
+		Embarked:    p.Embarked,
+	}
+}
+
+// Passenger converts the Parquet row back to a Passenger.
+func (r ParquetPassenger) Passenger() Passenger {
+	return Passenger{
+		PassengerID: int(r.PassengerID),
+		Survived:    int(r.Survived),
+		Pclass:      int(r.Pclass),
+		Name:        r.Name,
+		Sex:         r.Sex,
+		Age:         r.Age,
+		SibSp:       int(r.SibSp),
+		Parch:       int(r.Parch),
+		Ticket:      r.Ticket,
+		Fare:        r.Fare,
+		Cabin:       r.Cabin,
+		Embarked:    r.Embarked,
+	}
+}
@@ -15,12 +15,104 @@ type Passenger struct {
 	Embarked    *string  `json:"embarked,omitempty"`
 }
 
+// PassengerField is one field of a Passenger, named as in its JSON encoding.
+type PassengerField struct {
+	Name string
+	// Get returns the field's value, or nil for an optional field that is not set.
+	Get func(p *Passenger) any
+}
+
+// PassengerFields lists the fields of Passenger in declaration order. The
+// getters avoid reflecting over Passenger on every use.
+var PassengerFields = []PassengerField{
+	{"passengerId", func(p *Passenger) any { return p.PassengerID }},
+	{"survived", func(p *Passenger) any { return p.Survived }},
+	{"pClass", func(p *Passenger) any { return p.Pclass }},
+	{"name", func(p *Passenger) any { return p.Name }},
+	{"sex", func(p *Passenger) any { return p.Sex }},
+	{"age", func(p *Passenger) any { return optional(p.Age) }},
+	{"sibSp", func(p *Passenger) any { return p.SibSp }},
+	{"parch", func(p *Passenger) any { return p.Parch }},
+	{"ticket", func(p *Passenger) any { return p.Ticket }},
+	{"fare", func(p *Passenger) any { return optional(p.Fare) }},
+	{"cabin", func(p *Passenger) any { return optional(p.Cabin) }},
+	{"embarked", func(p *Passenger) any { return optional(p.Embarked) }},
+}
+
+// optional returns v, or an untyped nil if v is nil.
+func optional[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return v
+}
+
 type FareHistogram struct {
 	Percentiles []string `json:"percentiles"`
 	Counts      []int    `json:"counts"`
 }
 
+// BatchGetRequest is the body of a batch passenger lookup.
+type BatchGetRequest struct {
+	IDs []int `json:"ids" binding:"required" example:"1,2,3"`
+}
+
+// BatchGetResponse holds the passengers found by a batch lookup, in request order,
+// and the requested IDs that matched no passenger.
+type BatchGetResponse struct {
+	Passengers []Passenger `json:"passengers"`
+	Missing    []int       `json:"missing"`
+}
+
+// ImportError reports why one line of an imported CSV file was rejected.
+type ImportError struct {
+	Line    int    `json:"line" example:"12"`
+	Message string `json:"message" example:"Pclass must be 1, 2 or 3"`
+}
+
+// ImportSummary describes the outcome of a dataset import.
+type ImportSummary struct {
+	Mode     string        `json:"mode" example:"upsert"`
+	Inserted int           `json:"inserted"`
+	Updated  int           `json:"updated"`
+	Rejected int           `json:"rejected"`
+	Errors   []ImportError `json:"errors"`
+}
+
 // ErrorResponse represents a standard error message format.
 type ErrorResponse struct {
 	Message string `json:"message" example:"An error occurred"`
 }
+
+// ConsistencyReport lists the differences between the passengers held by two data sources.
+type ConsistencyReport struct {
+	Left  string `json:"left" example:"csv"`
+	Right string `json:"right" example:"sqlite"`
+	// LeftRows and RightRows count the passengers read from each source.
+	LeftRows  int `json:"leftRows" example:"891"`
+	RightRows int `json:"rightRows" example:"891"`
+	// LeftInvalid and RightInvalid count the records each source skipped as invalid.
+	LeftInvalid  int `json:"leftInvalid"`
+	RightInvalid int `json:"rightInvalid"`
+	// Consistent is true when both sources hold exactly the same passengers and
+	// neither skipped an invalid record.
+	Consistent  bool  `json:"consistent"`
+	OnlyInLeft  []int `json:"onlyInLeft"`
+	OnlyInRight []int `json:"onlyInRight"`
+	// DuplicateIDs lists the IDs held by more than one record of either source.
+	DuplicateIDs []int `json:"duplicateIds"`
+	// Mismatched counts the passengers found in both sources with at least one differing field.
+	Mismatched  int               `json:"mismatched"`
+	Differences []FieldDifference `json:"differences"`
+	// Truncated is set when there were more differences than listed.
+	Truncated bool `json:"truncated"`
+}
+
+// FieldDifference is a field whose value differs between two data sources.
+// Values are shown as JSON, so that null and surrounding spaces are visible.
+type FieldDifference struct {
+	PassengerID int    `json:"passengerId" example:"42"`
+	Field       string `json:"field" example:"fare"`
+	Left        string `json:"left" example:"7.25"`
+	Right       string `json:"right" example:"7.2500001"`
+}
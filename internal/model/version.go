@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// VersionInfo identifies the running build and the dataset it serves. It is returned by /version.
+type VersionInfo struct {
+	Version   string `json:"version" example:"v1.0.0"`
+	Revision  string `json:"revision,omitempty" example:"d6240a4c1f0e8b7a5d3c2b1a0f9e8d7c6b5a4f3e"`
+	GoVersion string `json:"goVersion" example:"go1.24.5"`
+	// Dataset is omitted when the data source cannot be read.
+	Dataset *DatasetInfo `json:"dataset,omitempty"`
+	// EmbeddedChecksum is the SHA-256 of the CSV file compiled into the binary
+	// and served by the "embedded" data source.
+	EmbeddedChecksum string `json:"embeddedChecksum" example:"d9d6e4d6fe0d7ef3ab3d2e0d8f7bd1a0e1c3f5b6a7c8d9e0f1a2b3c4d5e6f7a8"`
+}
+
+// DatasetInfo describes the dataset currently served.
+type DatasetInfo struct {
+	Source string `json:"source,omitempty" example:"embedded"`
+	// Hash is a SHA-256 digest over every passenger, equal across storage formats.
+	Hash       string     `json:"hash" example:"3f2a9c0e5b7d1e4f6a8c0b2d4e6f8a0c2e4b6d8f0a2c4e6b8d0f2a4c6e8b0d2f"`
+	Rows       int        `json:"rows" example:"891"`
+	Invalid    int        `json:"invalid" example:"0"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty"`
+	LoadedAt   time.Time  `json:"loadedAt"`
+}
@@ -0,0 +1,104 @@
+// Package ratelimit throttles clients with per-route-group token buckets and
+// enforces daily request quotas.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit configures a token bucket: it refills at Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed bool
+	// Limit is the bucket's capacity.
+	Limit int
+	// Remaining is the number of whole tokens left after this request.
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token is available, when not Allowed.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps one token bucket per client key.
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter returns a limiter applying limit to each key separately.
+func NewLimiter(limit Limit) *Limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &Limiter{limit: limit, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from key's bucket if one is available.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	} else if l.limit.Rate > 0 {
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+		b.last = now
+	}
+
+	d := Decision{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.duration(capacity - b.tokens)
+	return d
+}
+
+// duration returns how long it takes to refill the given number of tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if l.limit.Rate <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
+
+// sweep drops buckets that have been idle long enough to be full again, since
+// a fresh bucket behaves the same. It runs at most once a minute.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute || l.limit.Rate <= 0 {
+		return
+	}
+	l.lastSweep = now
+	full := l.duration(float64(l.limit.Burst))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
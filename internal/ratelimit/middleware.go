@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Policy holds the rate limits of each route group and the daily quota shared
+// by all of them.
+type Policy struct {
+	limiters map[string]*Limiter
+	quota    *Quota
+}
+
+// NewPolicy builds a policy from per-group limits. Groups without a limit, or
+// with a non-positive rate, are not throttled. quota may be nil.
+func NewPolicy(limits map[string]Limit, quota *Quota) *Policy {
+	p := &Policy{limiters: make(map[string]*Limiter), quota: quota}
+	for group, limit := range limits {
+		if limit.Rate > 0 {
+			p.limiters[group] = NewLimiter(limit)
+		}
+	}
+	return p
+}
+
+// clientKey identifies the caller: the authenticated principal when there is
+// one, otherwise the client IP address. The IP is only taken from
+// X-Forwarded-For when the engine trusts the peer as a proxy.
+func clientKey(c *gin.Context) string {
+	if p, ok := auth.PrincipalFrom(c); ok {
+		return p.ID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware enforces the group's rate limit and the daily quota. It must run
+// after authentication so that clients are keyed by principal.
+//
+// Responses carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers for the tighter of the two. Rejected requests get 429 Too Many
+// Requests with Retry-After.
+func (p *Policy) Middleware(group string) gin.HandlerFunc {
+	limiter := p.limiters[group]
+	if limiter == nil && p.quota == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		key := clientKey(c)
+
+		var rate Decision
+		if limiter != nil {
+			rate = limiter.Allow(key)
+			setHeaders(c, rate.Limit, rate.Remaining, rate.Reset)
+			if !rate.Allowed {
+				reject(c, rate.RetryAfter, "Rate limit exceeded, slow down")
+				return
+			}
+		}
+
+		if p.quota != nil {
+			quota, err := p.quota.Take(c.Request.Context(), key)
+			if err != nil {
+				// Failing open keeps the API available if the quota store breaks.
+				slog.ErrorContext(c.Request.Context(), "could not count request against quota", "client", key, "error", err)
+			} else {
+				if limiter == nil || quota.Remaining < rate.Remaining {
+					setHeaders(c, quota.Limit, quota.Remaining, quota.Reset)
+				}
+				if !quota.Allowed {
+					reject(c, quota.Reset, "Daily request quota exhausted")
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+func setHeaders(c *gin.Context, limit, remaining int, reset time.Duration) {
+	c.Header("RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+}
+
+func reject(c *gin.Context, retryAfter time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(max(seconds(retryAfter), 1)))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, model.ErrorResponse{Message: message})
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
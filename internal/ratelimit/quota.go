@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// QuotaStore counts requests per client key and UTC day.
+type QuotaStore interface {
+	// Increment records one request by key on day (formatted as 2006-01-02) and
+	// returns the number of requests made that day, including this one.
+	Increment(ctx context.Context, key, day string) (int, error)
+}
+
+// Quota caps the number of requests each client may make per UTC day.
+type Quota struct {
+	limit int
+	store QuotaStore
+	now   func() time.Time
+}
+
+// QuotaDecision is the outcome of counting a request against a quota.
+type QuotaDecision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the quota renews at midnight UTC.
+	Reset time.Duration
+}
+
+// NewQuota allows each key limit requests per day, counted in store.
+func NewQuota(limit int, store QuotaStore) *Quota {
+	return &Quota{limit: limit, store: store, now: time.Now}
+}
+
+// Take counts a request by key against today's quota.
+func (q *Quota) Take(ctx context.Context, key string) (QuotaDecision, error) {
+	now := q.now().UTC()
+	used, err := q.store.Increment(ctx, key, now.Format(time.DateOnly))
+	if err != nil {
+		return QuotaDecision{}, err
+	}
+	midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	return QuotaDecision{
+		Allowed:   used <= q.limit,
+		Limit:     q.limit,
+		Remaining: max(q.limit-used, 0),
+		Reset:     midnight.Sub(now),
+	}, nil
+}
+
+// MemoryQuotaStore counts requests in memory. Counts are lost on restart.
+type MemoryQuotaStore struct {
+	mu     sync.Mutex
+	day    string
+	counts map[string]int
+}
+
+// NewMemoryQuotaStore returns an empty in-memory store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{counts: make(map[string]int)}
+}
+
+// Increment implements QuotaStore. Only the current day is kept.
+func (s *MemoryQuotaStore) Increment(_ context.Context, key, day string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if day != s.day {
+		s.day = day
+		clear(s.counts)
+	}
+	s.counts[key]++
+	return s.counts[key], nil
+}
+
+// SQLiteQuotaStore persists request counts, so quotas survive restarts and can
+// be shared by replicas on the same volume.
+type SQLiteQuotaStore struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	lastDay string
+}
+
+// OpenSQLiteQuotaStore opens (creating if needed) the quota database at path.
+func OpenSQLiteQuotaStore(path string) (*SQLiteQuotaStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS quota_usage (
+		key TEXT NOT NULL,
+		day TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		PRIMARY KEY (key, day)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteQuotaStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteQuotaStore) Close() error {
+	return s.db.Close()
+}
+
+// Increment implements QuotaStore. Counts from earlier days are deleted when
+// the day changes.
+func (s *SQLiteQuotaStore) Increment(ctx context.Context, key, day string) (int, error) {
+	s.mu.Lock()
+	if day != s.lastDay {
+		s.lastDay = day
+		s.mu.Unlock()
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM quota_usage WHERE day < ?", day); err != nil {
+			return 0, err
+		}
+	} else {
+		s.mu.Unlock()
+	}
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `INSERT INTO quota_usage (key, day, count) VALUES (?, ?, 1)
+		ON CONFLICT (key, day) DO UPDATE SET count = count + 1
+		RETURNING count`, key, day).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiter(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter(Limit{Rate: 2, Burst: 3})
+	l.now = clock.now
+
+	for i := 2; i >= 0; i-- {
+		d := l.Allow("a")
+		assert.True(t, d.Allowed)
+		assert.Equal(t, i, d.Remaining)
+		assert.Equal(t, 3, d.Limit)
+	}
+	d := l.Allow("a")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, d.Reset)
+
+	assert.True(t, l.Allow("b").Allowed, "buckets are per key")
+
+	clock.advance(500 * time.Millisecond)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Allow("a").Allowed)
+
+	clock.advance(time.Hour)
+	d = l.Allow("a")
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 2, d.Remaining, "the bucket never holds more than the burst")
+	assert.Len(t, l.buckets, 1, "idle buckets are swept")
+}
+
+func testQuotaStore(t *testing.T, store QuotaStore) {
+	ctx := context.Background()
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)}
+	q := NewQuota(2, store)
+	q.now = clock.now
+
+	d, err := q.Take(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 1, d.Remaining)
+	assert.Equal(t, time.Hour, d.Reset)
+
+	q.Take(ctx, "a")
+	d, _ = q.Take(ctx, "a")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+
+	d, _ = q.Take(ctx, "b")
+	assert.True(t, d.Allowed, "quotas are per key")
+
+	clock.advance(time.Hour)
+	d, _ = q.Take(ctx, "a")
+	assert.True(t, d.Allowed, "quotas renew at midnight UTC")
+}
+
+func TestQuota_Memory(t *testing.T) {
+	testQuotaStore(t, NewMemoryQuotaStore())
+}
+
+func TestQuota_SQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.db")
+	store, err := OpenSQLiteQuotaStore(path)
+	assert.NoError(t, err)
+	testQuotaStore(t, store)
+	store.Close()
+
+	// Usage survives reopening the database.
+	store, err = OpenSQLiteQuotaStore(path)
+	assert.NoError(t, err)
+	defer store.Close()
+	n, err := store.Increment(context.Background(), "a", "2024-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := NewPolicy(map[string]Limit{"stats": {Rate: 1, Burst: 2}}, NewQuota(3, NewMemoryQuotaStore()))
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/stats", policy.Middleware("stats"), ok)
+	router.GET("/other", policy.Middleware("other"), ok)
+
+	get := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/stats", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+
+	get("/stats", "10.0.0.1")
+	w = get("/stats", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, get("/stats", "10.0.0.2").Code, "clients are keyed by IP")
+
+	// The quota is shared by all groups: two requests so far, one left.
+	w = get("/other", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	w = get("/other", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestClientKeyForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := func(trusted []string, remoteAddr, forwardedFor string) string {
+		router := gin.New()
+		assert.NoError(t, router.SetTrustedProxies(trusted))
+		router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, clientKey(c)) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr + ":1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "ip:10.0.0.1", key(nil, "10.0.0.1", "192.0.2.7"), "a spoofed header does not change the key")
+	assert.Equal(t, "ip:10.0.0.1", key(nil, "10.0.0.1", "192.0.2.8"))
+	assert.Equal(t, "ip:192.0.2.7", key([]string{"10.0.0.0/8"}, "10.0.0.1", "192.0.2.7"), "trusted proxies forward the client IP")
+	assert.Equal(t, "ip:172.16.0.1", key([]string{"10.0.0.0/8"}, "172.16.0.1", "192.0.2.7"))
+}
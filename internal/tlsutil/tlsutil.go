@@ -0,0 +1,181 @@
+// Package tlsutil builds the server's TLS configuration from certificate files
+// that are reloaded when they change on disk.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/filestamp"
+)
+
+// ClientAuth selects whether clients must present a certificate.
+type ClientAuth string
+
+const (
+	// ClientAuthNone does not ask for client certificates.
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthOptional verifies a client certificate if one is presented.
+	ClientAuthOptional ClientAuth = "optional"
+	// ClientAuthRequire rejects connections without a valid client certificate.
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// ParseClientAuth validates a client authentication mode. The empty string means none.
+func ParseClientAuth(s string) (ClientAuth, error) {
+	switch mode := ClientAuth(strings.ToLower(s)); mode {
+	case "", ClientAuthNone:
+		return ClientAuthNone, nil
+	case ClientAuthOptional, ClientAuthRequire:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid client_auth %q, valid modes are: none, optional, require", s)
+	}
+}
+
+func (m ClientAuth) tlsType() tls.ClientAuthType {
+	switch m {
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// Options configures a Reloader.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientAuth enables mTLS. ClientCAFile must then name a PEM bundle of the
+	// CAs trusted to issue client certificates.
+	ClientAuth   ClientAuth
+	ClientCAFile string
+}
+
+// Reloader serves the certificate, key and client CA bundle named in its
+// options, and picks up new versions of the files without a restart.
+type Reloader struct {
+	opts Options
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	stamp    string
+}
+
+// NewReloader loads the files once, failing if they are missing or invalid.
+func NewReloader(opts Options) (*Reloader, error) {
+	if opts.ClientAuth == "" {
+		opts.ClientAuth = ClientAuthNone
+	}
+	if opts.ClientAuth != ClientAuthNone && opts.ClientCAFile == "" {
+		return nil, errors.New("client_ca_file is required for client certificate verification")
+	}
+	r := &Reloader{opts: opts}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files lists the files the configuration depends on.
+func (r *Reloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientAuth != ClientAuthNone {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+// Reload reads the files again if they changed since the last load, and
+// reports whether anything was reloaded. On error the current certificate
+// stays in use, so a half-written rotation does not take the server down.
+func (r *Reloader) Reload() (bool, error) {
+	stamp, _, err := filestamp.Of(r.files()...)
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := stamp == r.stamp
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("could not load TLS certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.opts.ClientAuth != ClientAuthNone {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("could not read client CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("client CA bundle %s contains no certificates", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCA, r.stamp = &cert, pool, stamp
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Start polls the files every interval until ctx is cancelled.
+func (r *Reloader) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloaded, err := r.Reload()
+				if err != nil {
+					slog.Error("could not reload TLS certificates", "cert_file", r.opts.CertFile, "error", err)
+				} else if reloaded {
+					slog.Info("reloaded TLS certificates", "cert_file", r.opts.CertFile)
+				}
+			}
+		}
+	}()
+}
+
+// TLSConfig returns a server configuration that always uses the most recently
+// loaded certificate and client CA bundle.
+func (r *Reloader) TLSConfig() *tls.Config {
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	base.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.cert, nil
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		clientCA := r.clientCA
+		r.mu.RUnlock()
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientAuth = r.opts.ClientAuth.tlsType()
+		cfg.ClientCAs = clientCA
+		return cfg, nil
+	}
+	return base
+}
@@ -0,0 +1,185 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCA issues certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, usable by servers and clients.
+func (ca *testCA) issue(t *testing.T, name string, serial int64) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"Test"}},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+// startServer serves a handler echoing the client certificate subject over TLS.
+func startServer(t *testing.T, r *Reloader) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		subject, _ := auth.ClientSubject(c)
+		c.String(http.StatusOK, subject)
+	})
+	srv := httptest.NewUnstartedServer(router)
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func client(ca *testCA, cert *tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+}
+
+func TestReloader_Rotation(t *testing.T) {
+	ca := newTestCA(t, "server CA")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, "server-1", 2)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	r, err := NewReloader(Options{CertFile: certFile, KeyFile: keyFile})
+	assert.NoError(t, err)
+	srv := startServer(t, r)
+
+	servedCN := func() string {
+		resp, err := client(ca, nil).Get(srv.URL)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "server-1", servedCN())
+
+	reloaded, err := r.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files are not reloaded")
+
+	// A half-written rotation keeps the old certificate.
+	writeFile(t, certFile, []byte("garbage"))
+	_, err = r.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "server-1", servedCN())
+
+	certPEM, keyPEM = ca.issue(t, "server-2", 3)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	reloaded, err = r.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "server-2", servedCN())
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	serverCA, clientCA, otherCA := newTestCA(t, "server CA"), newTestCA(t, "client CA"), newTestCA(t, "other CA")
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	certPEM, keyPEM := serverCA.issue(t, "server", 2)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, clientCA.pem)
+
+	_, err := NewReloader(Options{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire})
+	assert.Error(t, err, "a CA bundle is required")
+
+	r, err := NewReloader(Options{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire, ClientCAFile: caFile})
+	assert.NoError(t, err)
+	srv := startServer(t, r)
+
+	clientCert := func(ca *testCA) *tls.Certificate {
+		certPEM, keyPEM := ca.issue(t, "dashboard", 5)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		assert.NoError(t, err)
+		return &cert
+	}
+
+	resp, err := client(serverCA, clientCert(clientCA)).Get(srv.URL)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "CN=dashboard,O=Test", string(body))
+
+	_, err = client(serverCA, nil).Get(srv.URL)
+	assert.Error(t, err, "a client certificate is required")
+
+	_, err = client(serverCA, clientCert(otherCA)).Get(srv.URL)
+	assert.Error(t, err, "certificates from other CAs are rejected")
+}
+
+func TestParseClientAuth(t *testing.T) {
+	mode, err := ParseClientAuth("")
+	assert.NoError(t, err)
+	assert.Equal(t, ClientAuthNone, mode)
+	mode, err = ParseClientAuth("Optional")
+	assert.NoError(t, err)
+	assert.Equal(t, ClientAuthOptional, mode)
+	_, err = ParseClientAuth("always")
+	assert.Error(t, err)
+}
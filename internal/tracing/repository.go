@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"iter"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+)
+
+// instrumentationName names the tracer used for spans created by this service.
+const instrumentationName = "github.com/dhope-nagesh/titanic-go-service"
+
+// Tracer returns the service tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Attribute keys set on repository spans.
+const (
+	rowsKey         = attribute.Key("titanic.rows")
+	passengerIDKey  = attribute.Key("titanic.passenger_id")
+	requestedIDsKey = attribute.Key("titanic.requested_ids")
+)
+
+// tracedRepository records a span for every call to the repository it wraps.
+type tracedRepository struct {
+	next   data.PassengerRepository
+	tracer trace.Tracer
+}
+
+// TraceRepository wraps repo so that each call is recorded as a child span of
+// the caller's context. Imports are forwarded when repo supports them.
+func TraceRepository(repo data.PassengerRepository) data.PassengerRepository {
+	return &tracedRepository{next: repo, tracer: Tracer()}
+}
+
+func (r *tracedRepository) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "repository."+op, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+}
+
+func (r *tracedRepository) GetAllPassengers(ctx context.Context) ([]model.Passenger, error) {
+	ctx, span := r.start(ctx, "GetAllPassengers")
+	passengers, err := r.next.GetAllPassengers(ctx)
+	span.SetAttributes(rowsKey.Int(len(passengers)))
+	End(span, err)
+	return passengers, err
+}
+
+func (r *tracedRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	ctx, span := r.start(ctx, "GetPassengerByID", passengerIDKey.Int(id))
+	p, err := r.next.GetPassengerByID(ctx, id)
+	if p != nil {
+		span.SetAttributes(rowsKey.Int(1))
+	}
+	End(span, err)
+	return p, err
+}
+
+func (r *tracedRepository) GetPassengersByIDs(ctx context.Context, ids []int) ([]model.Passenger, error) {
+	ctx, span := r.start(ctx, "GetPassengersByIDs", requestedIDsKey.Int(len(ids)))
+	passengers, err := r.next.GetPassengersByIDs(ctx, ids)
+	span.SetAttributes(rowsKey.Int(len(passengers)))
+	End(span, err)
+	return passengers, err
+}
+
+func (r *tracedRepository) GetFares(ctx context.Context) ([]float64, error) {
+	ctx, span := r.start(ctx, "GetFares")
+	fares, err := r.next.GetFares(ctx)
+	span.SetAttributes(rowsKey.Int(len(fares)))
+	End(span, err)
+	return fares, err
+}
+
+// Passengers spans the iteration from the first record to the last, or to
+// the point where the caller stops, and counts the records yielded.
+func (r *tracedRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		ctx, span := r.start(ctx, "Passengers")
+		rows := 0
+		var failed error
+		defer func() {
+			span.SetAttributes(rowsKey.Int(rows))
+			End(span, failed)
+		}()
+		for p, err := range r.next.Passengers(ctx) {
+			if err != nil {
+				failed = err
+			} else {
+				rows++
+			}
+			if !yield(p, err) {
+				return
+			}
+		}
+	}
+}
+
+func (r *tracedRepository) Version(ctx context.Context) (data.DatasetVersion, error) {
+	ctx, span := r.start(ctx, "Version")
+	v, err := r.next.Version(ctx)
+	span.SetAttributes(rowsKey.Int(v.Rows))
+	End(span, err)
+	return v, err
+}
+
+func (r *tracedRepository) Ping(ctx context.Context) error {
+	ctx, span := r.start(ctx, "Ping")
+	err := r.next.Ping(ctx)
+	End(span, err)
+	return err
+}
+
+func (r *tracedRepository) Close() error {
+	return r.next.Close()
+}
+
+// Import implements data.Importer, reporting data.ErrImportNotSupported when
+// the wrapped repository cannot import.
+func (r *tracedRepository) Import(ctx context.Context, mode data.ImportMode, records []data.ImportRecord) (model.ImportSummary, error) {
+	importer, ok := r.next.(data.Importer)
+	if !ok {
+		return model.ImportSummary{}, data.ErrImportNotSupported
+	}
+	ctx, span := r.start(ctx, "Import", attribute.String("titanic.import_mode", string(mode)), attribute.Int("titanic.records", len(records)))
+	summary, err := importer.Import(ctx, mode, records)
+	span.SetAttributes(
+		attribute.Int("titanic.inserted", summary.Inserted),
+		attribute.Int("titanic.updated", summary.Updated),
+		attribute.Int("titanic.rejected", summary.Rejected),
+	)
+	End(span, err)
+	return summary, err
+}
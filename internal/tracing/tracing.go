@@ -0,0 +1,101 @@
+// Package tracing configures OpenTelemetry tracing and W3C trace-context
+// propagation, and traces repository calls.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters that can be selected in Config.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// DefaultServiceName is used when Config.ServiceName is empty.
+const DefaultServiceName = "titanic-api"
+
+// Config selects where spans are exported.
+type Config struct {
+	// Exporter is "none", "stdout" or "otlp".
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector address, e.g. "localhost:4318".
+	// When empty the standard OTEL_EXPORTER_OTLP_* environment variables apply.
+	Endpoint string
+	// Insecure sends OTLP over plain HTTP, as local collectors usually expect.
+	Insecure bool
+	// SampleRatio is the fraction of new traces recorded. Requests that arrive
+	// with a sampled trace context are always recorded.
+	SampleRatio float64
+	// ServiceName identifies the service in the exported spans. It defaults
+	// to DefaultServiceName.
+	ServiceName string
+	// Writer receives stdout spans. It defaults to os.Stdout.
+	Writer io.Writer
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// function flushes buffered spans and must be called before exiting. With the
+// "none" exporter only propagation is set up and spans are discarded.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch strings.ToLower(cfg.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := cfg.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q, valid exporters are: none, stdout, otlp", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name)))
+	if err != nil {
+		return nil, err
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dhope-nagesh/titanic-go-service/internal/data"
+	"github.com/dhope-nagesh/titanic-go-service/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeRepository embeds the interface so only the methods under test need bodies.
+type fakeRepository struct {
+	data.PassengerRepository
+	passengers []model.Passenger
+	err        error
+}
+
+func (f *fakeRepository) GetPassengerByID(ctx context.Context, id int) (*model.Passenger, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &model.Passenger{PassengerID: id}, nil
+}
+
+func (f *fakeRepository) Passengers(ctx context.Context) iter.Seq2[model.Passenger, error] {
+	return func(yield func(model.Passenger, error) bool) {
+		for _, p := range f.passengers {
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+
+// record installs a global tracer provider that keeps every ended span.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTraceRepository(t *testing.T) {
+	recorder := record(t)
+	fake := &fakeRepository{passengers: []model.Passenger{{PassengerID: 1}, {PassengerID: 2}, {PassengerID: 3}}}
+	repo := TraceRepository(fake)
+
+	ctx, parent := Tracer().Start(context.Background(), "request")
+	_, err := repo.GetPassengerByID(ctx, 7)
+	assert.NoError(t, err)
+	for range repo.Passengers(ctx) {
+		break
+	}
+	fake.err = errors.New("disk on fire")
+	_, err = repo.GetPassengerByID(ctx, 8)
+	assert.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 4)
+
+	byID := spans[0]
+	assert.Equal(t, "repository.GetPassengerByID", byID.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), byID.Parent().SpanID())
+	assert.Equal(t, int64(7), attributes(byID)[passengerIDKey].AsInt64())
+	assert.Equal(t, int64(1), attributes(byID)[rowsKey].AsInt64())
+
+	// The caller stopped after the first passenger.
+	stream := spans[1]
+	assert.Equal(t, "repository.Passengers", stream.Name())
+	assert.Equal(t, int64(1), attributes(stream)[rowsKey].AsInt64())
+
+	failed := spans[2]
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, "disk on fire", failed.Status().Description)
+}
+
+func TestTraceRepositoryImportNotSupported(t *testing.T) {
+	repo := TraceRepository(&fakeRepository{})
+	_, err := repo.(data.Importer).Import(context.Background(), data.ImportUpsert, nil)
+	assert.ErrorIs(t, err, data.ErrImportNotSupported)
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := record(t)
+	// The "none" exporter installs the propagator and leaves the provider alone.
+	_, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(otelgin.Middleware("test"))
+	repo := TraceRepository(&fakeRepository{})
+	router.GET("/passengers/:id", func(c *gin.Context) {
+		repo.GetPassengerByID(c.Request.Context(), 1)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/passengers/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	}
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].Parent().SpanID().String())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: "stdout", Writer: &out, ServiceName: "titanic-test"})
+	assert.NoError(t, err)
+	_, span := Tracer().Start(context.Background(), "work")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, out.String(), `"Name":"work"`)
+	assert.Contains(t, out.String(), "titanic-test")
+
+	_, err = Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.ErrorContains(t, err, "invalid tracing exporter")
+}
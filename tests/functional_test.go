@@ -1,7 +1,9 @@
 package tests
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"github.com/dhope-nagesh/titanic-go-service/internal/cache"
 	"github.com/dhope-nagesh/titanic-go-service/internal/data"
 	"github.com/dhope-nagesh/titanic-go-service/internal/handler"
 	"github.com/dhope-nagesh/titanic-go-service/internal/model"
@@ -10,6 +12,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -139,3 +142,117 @@ func TestFunctionalGetFareHistogram(t *testing.T) {
 	assert.Equal(t, 10, len(histogram.Counts), "Histogram should have 10 bins")
 	assert.Equal(t, 10, len(histogram.Percentiles), "Histogram should have 10 labels")
 }
+
+// TestFunctionalFareHistogramNotModified tests that a repeated request with the returned ETag gets a 304.
+func TestFunctionalFareHistogramNotModified(t *testing.T) {
+	// Arrange
+	router := setupFunctionalTestServer(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/stats/fare_histogram", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/stats/fare_histogram", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+}
+
+// TestFunctionalCachedFareHistogram tests that the cached histogram matches the computed one.
+func TestFunctionalCachedFareHistogram(t *testing.T) {
+	// Arrange
+	repo, err := data.NewSQLiteRepository("../data/titanic.db")
+	assert.NoError(t, err)
+	defer repo.Close()
+	cached := cache.NewRepository(repo, cache.Config{})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.NewAPIHandler(cached, handler.WithCache(cached)).RegisterRoutes(router)
+	uncached := setupFunctionalTestServer(t)
+
+	get := func(router *gin.Engine) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/stats/fare_histogram", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	// Act
+	want := get(uncached)
+	first, second := get(router), get(router)
+
+	// Assert
+	assert.Equal(t, want, first)
+	assert.Equal(t, want, second)
+	assert.Positive(t, cached.Stats().Hits, "the second request is served from the cache")
+}
+
+// TestFunctionalBatchGetPassengers tests looking up several passengers in one request.
+func TestFunctionalBatchGetPassengers(t *testing.T) {
+	// Arrange
+	router := setupFunctionalTestServer(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/passengers:batchGet", strings.NewReader(`{"ids":[4,9999,2]}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp model.BatchGetResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Passengers, 2)
+	assert.Equal(t, "Futrelle, Mrs. Jacques Heath (Lily May Peel)", resp.Passengers[0].Name)
+	assert.Equal(t, 2, resp.Passengers[1].PassengerID)
+	assert.Equal(t, []int{9999}, resp.Missing)
+}
+
+// TestFunctionalExportCSV tests downloading a filtered CSV export.
+func TestFunctionalExportCSV(t *testing.T) {
+	// Arrange
+	router := setupFunctionalTestServer(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/export?format=csv&pClass=1&survived=1", nil)
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `^attachment; filename="titanic-[0-9a-f]{12}\.csv"$`, w.Header().Get("Content-Disposition"))
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, "PassengerId", records[0][0])
+	assert.Equal(t, 136, len(records)-1, "136 first-class passengers survived")
+	for _, record := range records[1:] {
+		assert.Equal(t, "1", record[1])
+		assert.Equal(t, "1", record[2])
+	}
+}
+
+// TestFunctionalExportInvalidFormat tests that an unknown export format is rejected.
+func TestFunctionalExportInvalidFormat(t *testing.T) {
+	// Arrange
+	router := setupFunctionalTestServer(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/export?format=pdf", nil)
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}